@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/golden"
+)
+
+func init() {
+	register("breaker", "Circuit breakers: failing fast while a website is down", breakerLesson,
+		golden.Replace(`127\.0\.0\.1:\d+`, "SITE"), // the test server listens on a random port
+	)
+}
+
+func breakerLesson(w io.Writer) {
+	// When a website is down, every request to it fails, often only after waiting for the full timeout.
+	// A circuit breaker remembers that: after Threshold failures in a row the circuit of the host "opens",
+	// and the requests to it fail straight away with fetch.ErrCircuitOpen, without being made.
+	// After Cooldown the circuit is "half-open" and lets a few probe requests through: if they succeed the circuit "closes" again, otherwise it reopens.
+
+	// The website is a local test server which can be switched down and up.
+	var down atomic.Bool
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+		if down.Load() {
+			http.Error(rw, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(rw, "ok")
+	}))
+	defer srv.Close()
+
+	// The cool-down is timed by a fake clock (see the clock package), so the lesson doesn't wait 30 seconds and its output is the same on every run.
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fetcher := fetch.Fetcher{
+		Timeout: 5 * time.Second,
+		Breaker: &fetch.Breaker{
+			Threshold: 3,
+			Cooldown:  30 * time.Second,
+			Probes:    2,
+			Clock:     fake,
+			OnStateChange: func(host string, from, to fetch.CircuitState) {
+				fmt.Fprintf(w, "    circuit of %s: %s -> %s\n", host, from, to)
+			},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	check := func(times int) {
+		for range times {
+			res := fetcher.Fetch(ctx, srv.URL)
+			status := res.Status
+			if res.StatusCode == 0 {
+				status = "no request (" + string(res.Kind) + ")"
+			}
+			fmt.Fprintf(w, "  %-30s circuit %s\n", status, res.Circuit)
+		}
+	}
+
+	fmt.Fprintln(w, "The website is up:")
+	check(2)
+	down.Store(true)
+	fmt.Fprintln(w, "The website goes down, the third failure in a row opens the circuit:")
+	check(3)
+	before := served.Load()
+	fmt.Fprintln(w, "While the circuit is open, the requests aren't even made:")
+	check(3)
+	fmt.Fprintln(w, "Requests reaching the website while open:", served.Load()-before)
+
+	fake.Advance(30 * time.Second)
+	fmt.Fprintln(w, "After the cool-down, a probe finds the website still down and reopens the circuit:")
+	check(2)
+
+	fake.Advance(30 * time.Second)
+	down.Store(false)
+	fmt.Fprintln(w, "The website is back up, 2 successful probes close the circuit:")
+	check(3)
+}
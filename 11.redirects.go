@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/golden"
+)
+
+func init() {
+	register("redirects", "Redirect chains and TLS certificates of HTTPS servers", redirectsLesson,
+		golden.Replace(`127\.0\.0\.1:\d+`, "SITE"), // the test server listens on a random port
+		// TLS 1.3 picks AES-GCM or ChaCha20 depending on whether the CPU has AES instructions.
+		golden.Replace(`TLS_(AES_128_GCM_SHA256|CHACHA20_POLY1305_SHA256)`, "TLS_CIPHER"),
+		golden.Replace(`\d{4}-\d{2}-\d{2}`, "DATE"), // the expiry of the test certificate depends on the Go version
+		golden.Replace(`in \d+ days`, "in N days"),
+	)
+}
+
+func redirectsLesson(w io.Writer) {
+	// A server answers a request with a 3xx status and a Location header to send the client somewhere else: a redirect.
+	// http.Client follows redirects on its own, so the response it returns is the one of the last URL.
+	// The fetcher records every hop of the chain in Result.Redirects, and Result.URL is the final URL.
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+	mux.Handle("/new", http.RedirectHandler("/final", http.StatusFound))
+	mux.HandleFunc("/final", func(rw http.ResponseWriter, r *http.Request) { fmt.Fprint(rw, "you made it") })
+	// A page redirecting to itself would be followed forever, MaxRedirects stops that.
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+
+	// httptest.NewTLSServer serves HTTPS using a certificate made for tests, which only the client of srv.Client() trusts.
+	// It is NewUnstartedServer and StartTLS here, to silence the log of the failed handshake at the end.
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	fetcher := fetch.Fetcher{
+		Client:       srv.Client(),
+		Timeout:      5 * time.Second,
+		MaxRedirects: 5,
+		// The test certificate expires decades from now, 100 years makes it "soon" enough for a warning.
+		CertExpiryWarning: 100 * 365 * 24 * time.Hour,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res := fetcher.Fetch(ctx, srv.URL+"/old")
+	fmt.Fprintf(w, "%s -> %s: %s\n", res.Website, res.URL, res.Status)
+	for i, hop := range res.Redirects {
+		fmt.Fprintf(w, "  hop %d: %s answered %s\n", i+1, hop.URL, hop.Status)
+	}
+
+	// Result.TLS describes the connection: the version of the protocol and the cipher suite both sides agreed on, and the certificate of the server.
+	// A certificate is only valid until NotAfter, after which clients refuse to connect: checking websites is a good place to catch it early.
+	if res.TLS != nil {
+		fmt.Fprintf(w, "TLS version: %s, cipher suite: %s\n", res.TLS.Version, res.TLS.CipherSuite)
+		fmt.Fprintf(w, "Certificate of %q issued by %q, expires on %s\n", res.TLS.Subject, res.TLS.Issuer, res.TLS.NotAfter.Format(time.DateOnly))
+	}
+	for _, warning := range res.Warnings {
+		fmt.Fprintln(w, "Warning:", warning)
+	}
+
+	loop := fetcher.Fetch(ctx, srv.URL+"/loop")
+	fmt.Fprintf(w, "\n%s: error (%s): %v\n", loop.Website, loop.Kind, loop.Err)
+	// The redirect which went over the limit is part of the chain too, although it wasn't followed.
+	fmt.Fprintf(w, "Redirects received before giving up: %d\n", len(loop.Redirects))
+
+	// Negative MaxRedirects doesn't follow redirects at all, the redirect itself is the result.
+	fetcher.MaxRedirects = -1
+	res = fetcher.Fetch(ctx, srv.URL+"/old")
+	fmt.Fprintf(w, "\nWithout following: %s, Location: %s\n", res.Status, res.Header.Get("Location"))
+
+	// The client of http.DefaultClient doesn't trust the test certificate, so the TLS handshake fails.
+	fetcher.Client = nil
+	res = fetcher.Fetch(ctx, srv.URL+"/final")
+	fmt.Fprintf(w, "Untrusted certificate: error kind %s\n", res.Kind)
+}
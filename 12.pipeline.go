@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ISanviI/LearnGo/leak"
+	"github.com/ISanviI/LearnGo/pipeline"
+)
+
+func init() {
+	register("pipeline", "Pipelines: stages connected by channels, fan-out, fan-in and cancellation", pipelineLesson)
+}
+
+func pipelineLesson(w io.Writer) {
+	// A pipeline is a series of stages connected by channels: every stage receives values from its input channel, processes them and sends the results on its output channel.
+	// The stages run concurrently, a value can be in the second stage while the next one is in the first.
+	// The rules that keep a pipeline correct, which the pipeline package follows for every stage:
+	// 1. A stage closes its output channel once it won't send anymore, so that the next stage's `for range` ends: the sender closes, never the receiver.
+	// 2. Sending and receiving always watch ctx.Done() too, so that a stage blocked on a stage that stopped is released when the pipeline is cancelled.
+	// 3. The first error cancels the context, which stops all the stages, and Wait returns that error once every goroutine has returned.
+	before := leak.Take()
+	ctx := context.Background()
+
+	// Source -> Stage -> Sink: squares of 1 to 10, computed by 3 workers (a fan-out), kept in order.
+	p := pipeline.New(ctx)
+	numbers := pipeline.FromSlice(p, 0, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	squares := pipeline.Map(p, numbers, pipeline.Options{Workers: 3, Buffer: 2, Ordered: true},
+		func(_ context.Context, n int) (int, error) { return n * n, nil })
+	var out []string
+	pipeline.Drain(p, squares, func(_ context.Context, sq int) error {
+		out = append(out, fmt.Sprint(sq))
+		return nil
+	})
+	err := p.Wait()
+	fmt.Fprintf(w, "Squares: %s (error: %v)\n", strings.Join(out, " "), err)
+
+	// Fan-out and fan-in by hand: 3 channels share the words, a stage per channel counts letters, Merge joins the counts back into one channel.
+	// The order of the merged values depends on which stage is faster, a sum doesn't care.
+	p = pipeline.New(ctx)
+	words := pipeline.FromSlice(p, 0, strings.Fields("the quick brown fox jumps over the lazy dog"))
+	var counted []<-chan int
+	for _, branch := range pipeline.FanOut(p, words, 3, 0) {
+		counted = append(counted, pipeline.Map(p, branch, pipeline.Options{}, func(_ context.Context, word string) (int, error) {
+			return len(word), nil
+		}))
+	}
+	total := 0
+	pipeline.Drain(p, pipeline.Merge(p, 0, counted...), func(_ context.Context, n int) error {
+		total += n
+		return nil
+	})
+	err = p.Wait()
+	fmt.Fprintf(w, "Letters: %d (error: %v)\n", total, err)
+
+	// An error in any stage stops the whole pipeline, the source included, even though it would go on forever.
+	p = pipeline.New(ctx)
+	naturals := pipeline.Generate(p, 0, func(ctx context.Context, emit func(int) error) error {
+		for n := 1; ; n++ {
+			if err := emit(n); err != nil {
+				return err
+			}
+		}
+	})
+	errTooBig := errors.New("too big")
+	checked := pipeline.Map(p, naturals, pipeline.Options{Workers: 4}, func(_ context.Context, n int) (int, error) {
+		if n > 100 {
+			return 0, errTooBig
+		}
+		return n, nil
+	})
+	pipeline.Drain(p, checked, func(context.Context, int) error { return nil })
+	err = p.Wait()
+	fmt.Fprintf(w, "Endless source stopped by a stage: %v (is errTooBig: %t)\n", err, errors.Is(err, errTooBig))
+
+	// Cancelling the parent context stops a pipeline from outside, e.g. on Ctrl+C.
+	cctx, cancel := context.WithCancel(ctx)
+	p = pipeline.New(cctx)
+	naturals = pipeline.Generate(p, 0, func(ctx context.Context, emit func(int) error) error {
+		for n := 1; ; n++ {
+			if err := emit(n); err != nil {
+				return err
+			}
+		}
+	})
+	received := 0
+	pipeline.Drain(p, naturals, func(context.Context, int) error {
+		if received++; received == 1000 {
+			cancel()
+		}
+		return nil
+	})
+	err = p.Wait()
+	fmt.Fprintf(w, "Endless source stopped by cancelling: %v\n", err)
+
+	// Every goroutine of the 4 pipelines has returned: a stage that forgot rule 1 or 2 would still be blocked, and listed here (see the leaks lesson).
+	// A goroutine only exits a moment after its wg.Done() lets Wait return, which Leaked waits for.
+	leaked := before.Leaked(leak.DefaultWait)
+	fmt.Fprintln(w, "Goroutines leaked:", len(leaked))
+	for _, g := range leaked {
+		fmt.Fprintf(w, "    [%s] in %s\n", g.State, g.Function)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/pubsub"
+)
+
+func init() {
+	register("pubsub", "Publish/subscribe: topics, wildcards and full queues", pubsubLesson)
+}
+
+func pubsubLesson(w io.Writer) {
+	// With publish/subscribe, a publisher sends a message to a topic without knowing who receives it,
+	// and every subscriber of the topic gets a copy in its own queue, a buffered channel.
+	// The broker (see the pubsub package) is generic over the type of the messages.
+	broker := pubsub.NewBroker[string]()
+	ctx := context.Background()
+
+	// "*" matches one part of a topic, ">" the rest of it.
+	patterns := []string{"site.>", "site.*.down", "site.alpha.*"}
+	subs := make([]*pubsub.Subscription[string], len(patterns))
+	for i, pattern := range patterns {
+		subs[i], _ = broker.Subscribe(pattern, pubsub.Options{Buffer: 10})
+	}
+	for _, topic := range []string{"site.alpha.up", "site.beta.down", "site.alpha.down", "log.info"} {
+		broker.Publish(ctx, topic, "message to "+topic)
+	}
+	for i, s := range subs {
+		fmt.Fprintf(w, "%-14s received: %s\n", patterns[i], strings.Join(queued(s), ", "))
+	}
+
+	// A subscriber slower than the publishers fills its queue, the policy of the subscription decides what happens then.
+	fmt.Fprintln(w, "\nPublishing 1 to 5 to queues of 2 messages which nobody receives from:")
+	for _, policy := range []pubsub.Policy{pubsub.Block, pubsub.DropOldest, pubsub.DropNewest} {
+		topic := "tick." + policy.String()
+		s, _ := broker.Subscribe(topic, pubsub.Options{Buffer: 2, Policy: policy})
+		blocked := 0
+		for n := 1; n <= 5; n++ {
+			// Block waits for room, so the context must give up at some point.
+			pctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			if err := broker.Publish(pctx, topic, fmt.Sprint(n)); err != nil {
+				blocked++
+			}
+			cancel()
+		}
+		fmt.Fprintf(w, "%-12s queued: %-5s dropped: %d, publishes timed out: %d\n", policy, strings.Join(queued(s), " "), s.Dropped(), blocked)
+	}
+
+	// A handler is a function called with every message, the broker runs it in a goroutine of its own: higher order functions at work.
+	var mu sync.Mutex
+	var alerts []string
+	stop, _ := broker.Handle("site.*.down", pubsub.Options{Buffer: 10}, func(m pubsub.Message[string]) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, "ALERT "+m.Topic)
+	})
+	broker.Publish(ctx, "site.gamma.down", "")
+	broker.Publish(ctx, "site.gamma.up", "")
+	stop() // waits for the handler to handle the queued messages
+	fmt.Fprintln(w, "\nHandled:", alerts)
+
+	// Sending on a closed channel panics, so the broker never closes a channel while a publisher could be sending on it.
+	// Publishing after unsubscribing, or while unsubscribing, is safe.
+	// The channel is closed once the messages queued before unsubscribing have been received, so `for range` ends.
+	subs[0].Unsubscribe()
+	err := broker.Publish(ctx, "site.alpha.up", "after unsubscribe")
+	left := 0
+	for range subs[0].C() {
+		left++
+	}
+	fmt.Fprintf(w, "Publish after Unsubscribe: %v, messages left in the queue: %d\n", err, left)
+
+	broker.Close()
+	for range subs[1].C() {
+	}
+	fmt.Fprintln(w, "Publish after Close:", broker.Publish(ctx, "site.alpha.up", ""))
+}
+
+// queued receives the messages waiting in the queue of s without blocking.
+func queued(s *pubsub.Subscription[string]) []string {
+	var payloads []string
+	for {
+		select {
+		case m := <-s.C():
+			payloads = append(payloads, m.Payload)
+		default:
+			return payloads
+		}
+	}
+}
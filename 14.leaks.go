@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/golden"
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+func init() {
+	register("leaks", "Goroutine leaks: finding goroutines blocked forever on a channel or a lock", leaksLesson,
+		// Under `go test` the functions of package main are named after the import path of the module.
+		golden.Replace(`github\.com/ISanviI/LearnGo\.`, "main."),
+	)
+}
+
+// The goroutines of the test server and the keep-alive connections to it come and go with the connections, they aren't leaks of the lesson.
+var leakIgnore = []string{"net/http.(*conn).serve", "net/http.(*persistConn)"}
+
+// request fetches website and sends the Result on channel, calling wg.Done() when it returns, as it is meant to be run as a goroutine.
+// It can't be cancelled and blocks until channel is received from, so it leaks when the receiver gives up: this is the leak the lesson finds.
+// Fetcher.FetchAll is the fixed version.
+func request(website string, channel chan fetch.Result, wg *sync.WaitGroup) {
+	defer wg.Done()
+	var f fetch.Fetcher
+	channel <- f.Fetch(context.Background(), website)
+}
+
+func leaksLesson(w io.Writer) {
+	// A goroutine isn't garbage collected while it runs, and a goroutine blocked on a channel nobody will ever use, or on a lock nobody will unlock, runs forever.
+	// Such a leaked goroutine keeps its stack and everything it references, and a server leaking one per request runs out of memory at some point.
+	// The leak package finds them: a Snapshot of the goroutines running before some code, compared with the ones still running after it
+	// (`learngo golden` checks every lesson this way, and `learngo run -leaks` does too).
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "ok")
+	}))
+	defer srv.Close()
+	websites := []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}
+
+	// The bare pattern the concurrency lesson started from: a goroutine per website sending on an unbuffered channel.
+	// The receiver only wants the first result and gives up, so the two other goroutines block on sending forever.
+	before := leak.Take()
+	channel := make(chan fetch.Result)
+	var wg sync.WaitGroup
+	for _, website := range websites {
+		wg.Add(1)
+		go request(website, channel, &wg)
+	}
+	first := <-channel
+	fmt.Fprintln(w, "First result:", first.Status)
+	// Leaked waits a little for the goroutines to return before calling them leaked, as they often return a moment after the code which started them.
+	leaked := before.Leaked(200*time.Millisecond, leakIgnore...)
+	fmt.Fprintln(w, "Goroutines leaked by request:", len(leaked))
+	for _, g := range leaked {
+		// The state and the stack tell where the goroutine is stuck, the full stack is in g.Stack.
+		fmt.Fprintf(w, "    [%s] in %s\n", g.State, g.Function)
+	}
+	// Here they can still be released, as the channel is at hand.
+	for range len(websites) - 1 {
+		<-channel
+	}
+	wg.Wait()
+	fmt.Fprintln(w, "After receiving the other results:", len(before.Leaked(time.Second, leakIgnore...)))
+
+	// Fetcher.FetchAll sends on a channel buffered for all the results, so giving up after the first one (and cancelling the others) leaks nothing.
+	before = leak.Take()
+	ctx, cancel := context.WithCancel(context.Background())
+	var f fetch.Fetcher
+	first = <-f.FetchAll(ctx, websites)
+	cancel()
+	fmt.Fprintf(w, "\nFetchAll, first result %s, goroutines leaked: %d\n", first.Status, len(before.Leaked(time.Second, leakIgnore...)))
+
+	// A lock leaks goroutines too: a worker returning without unlocking leaves every other worker blocked in Lock.
+	// `defer mu.Unlock()` right after mu.Lock() rules that out.
+	before = leak.Take()
+	var (
+		mu        sync.Mutex
+		squareSum int
+	)
+	mu.Lock() // the forgetful worker
+	wg.Add(3)
+	for id := 1; id <= 3; id++ {
+		go update2(io.Discard, id, &squareSum, &mu, &wg)
+	}
+	leaked = before.Leaked(200*time.Millisecond, leakIgnore...)
+	fmt.Fprintln(w, "\nGoroutines leaked by a lock never unlocked:", len(leaked))
+	for _, g := range leaked {
+		fmt.Fprintf(w, "    [%s] in %s\n", g.State, g.Function)
+	}
+	mu.Unlock()
+	wg.Wait()
+	fmt.Fprintln(w, "After unlocking:", len(before.Leaked(time.Second, leakIgnore...)), "square sum:", squareSum)
+
+	// The mutexes and pipeline lessons check their own goroutines the same way.
+	// In a test, `defer leak.Verify(t)()` fails the test when goroutines leaked (see fetch/fetch_test.go for tests and a benchmark doing so).
+}
@@ -1,62 +1,27 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"io"
 
-// **Structs** - Group related data together.
-// Nested structs (another structure as a member in the current struct while specifying both a different variable name for it and the name of another struct) are also possible in Go.
-type Person struct {
-	Name string
-	Age  int
-}
-// Methods on structs
-func (p *Person) updateAge(age int) { // Method with receiver type Person
-	p.Age = age
-	Greet(*p)
-}
-
-func Greet(p Person) int {
-	fmt.Println("Hello, my name is " + p.Name)
-	fmt.Println("I am", p.Age, "years old")
-	return 1
-}
-
-// **Interfaces** - Define a contract that types can implement, more like a blueprint for methods.
-// For example like abstract classes in C++ (methods must be redefined in child classes during inheritance, provides a blueprint)
-type Shape interface {
-	Area() float64 // Method signature
-}
-type Circle struct {
-	Radius float64
-}
-
-func (c Circle) Area() float64 {
-	return 3.14 * c.Radius * c.Radius // Implementing the Area method for Circle
-}
+	"github.com/ISanviI/LearnGo/people"
+	"github.com/ISanviI/LearnGo/shapes"
+)
 
-type Rectangle struct {
-	Width, Height float64
-}
+// The Person struct lives in the people package and the Shape interface with its implementations in the shapes package.
+// Only the identifiers starting with a CAPITAL LETTER (exported) can be used from here.
 
-func (r Rectangle) Area() float64 {
-	return r.Width * r.Height // Implementing the Area method for Rectangle
-}
-func PrintArea(s Shape) { // Function that takes an interface type
-	// Type Assertions
-	if circle, ok := s.(Circle); ok {
-		fmt.Println("Circle Area:", circle.Area()) // Calls the Area method of the Shape interface
-	} else if rectangle, ok := s.(Rectangle); ok {
-		fmt.Println("Rectangle Area:", rectangle.Area())
-	} else {
-		fmt.Println("Unknown shape type")
-	}
+func init() {
+	register("custom_ds", "Structs, methods, embedding and interfaces", customDSLesson)
 }
 
-// func main() {
+func customDSLesson(w io.Writer) {
 	// **Structs**
 	// Creating a new Person instance
-	person := Person{Name: "Alice", Age: 30}
-	Greet(person)
-	person.updateAge(35)
+	person := people.Person{Name: "Alice", Age: 30}
+	people.Greet(w, person)
+	person.UpdateAge(35)
+	people.Greet(w, person)
 
 	// Anonymous Structs - Useful for quick, one-off data structures. (Useful for nested structs)
 	anonymous := struct {
@@ -66,7 +31,7 @@ func PrintArea(s Shape) { // Function that takes an interface type
 		Name: "Bob",
 		Age:  25,
 	}
-	fmt.Println("Anonymous Struct - Name:", anonymous.Name, ", Age:", anonymous.Age)
+	fmt.Fprintln(w, "Anonymous Struct - Name:", anonymous.Name, ", Age:", anonymous.Age)
 
 	type CarT1 struct {
 		Company string
@@ -104,14 +69,14 @@ func PrintArea(s Shape) { // Function that takes an interface type
 			Diameter: 17.0,
 		},
 	}
-	fmt.Println("Car wheel's width (using car.width):", car.Width)
+	fmt.Fprintln(w, "Car wheel's width (using car.width):", car.Width)
 
 	// **Interfaces**
-	circle := Circle{Radius: 5.0}
-	rectangle := Rectangle{Width: 4.0, Height: 6.0}
+	circle := shapes.Circle{Radius: 5.0}
+	rectangle := shapes.Rectangle{Width: 4.0, Height: 6.0}
 	// PrintArea() can have any struct as an argument that implements the Shape interface
 	// This is polymorphism in Go, where different types can be treated as the same type (interface) if they implement the same methods.
-	PrintArea(circle)    // Calls the Area method for Circle
-	PrintArea(rectangle) // Calls the Area method for Rectangle
+	shapes.PrintArea(w, circle)    // Calls the Area method for Circle
+	shapes.PrintArea(w, rectangle) // Calls the Area method for Rectangle
 
 }
@@ -1,5 +1,12 @@
 package main
 
+import (
+	"fmt"
+	"io"
+
+	"github.com/ISanviI/LearnGo/golden"
+)
+
 type car struct {
 	Company string
 	Price   int
@@ -13,16 +20,21 @@ func (c *car) updateCompany(newCompany string) {
 	c.Company = newCompany
 }
 
-// func main() {
+func init() {
+	register("pointers", "Pointers and value vs pointer receivers", pointersLesson, golden.Addresses)
+}
+
+func pointersLesson(w io.Writer) {
 	// **Pointers**
 	// Pointers in Go are similar to C/C++ pointers, but they are safer and easier to use.
 	// Deferencing a nil pointer could cause severe bugs and panics.
+	// The builtin println() writes to stderr and is only meant for debugging, fmt prints pointers as hexadecimal addresses too.
 	var a int = 42
 	var p *int = &a // p is a pointer to the variable a
-	println("Value of p (address of a):", p)
-	println("Value pointed by p:", *p)
+	fmt.Fprintln(w, "Value of p (address of a):", p)
+	fmt.Fprintln(w, "Value pointed by p:", *p)
 	*p = 100
-	println("New value of a after modifying through pointer p:", a)
+	fmt.Fprintln(w, "New value of a after modifying through pointer p:", a)
 
 	c1 := car{
 		Company: "Toyota",
@@ -31,5 +43,5 @@ func (c *car) updateCompany(newCompany string) {
 	// No need to use `&` to pass the struct to a function, as it is passed by reference by default.
 	c1.updatePrice(35000)     // This will not change the original car's price as it is passed by value
 	c1.updateCompany("Honda") // This will change the original car's company as it is passed by reference
-	println("Car after updatePrice:", c1.Company, "Price:", c1.Price)
+	fmt.Fprintln(w, "Car after updatePrice:", c1.Company, "Price:", c1.Price)
 }
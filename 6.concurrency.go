@@ -1,41 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
-	"sync"
 	"time"
-)
 
-type response struct {
-	Website   string
-	Status    string
-	result    string
-	timeTaken int64
-}
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/ratelimit"
+	"github.com/ISanviI/LearnGo/stats"
+)
 
-func request(website string, channel chan response, wg *sync.WaitGroup) {
-	defer wg.Done()
-	start := time.Now()
-	res, err := http.Get(website)
-	requiredTime := time.Since(start).Milliseconds()
-	if err != nil {
-		channel <- response{Website: website, Status: res.Status, result: err.Error(), timeTaken: requiredTime}
-		return
-	}
-	body, err := io.ReadAll(res.Body)
-	// buffer := make([]byte, 1024)
-	// n, err := res.Body.Read(buffer)
-	if err != nil {
-		channel <- response{Website: website, Status: res.Status, result: err.Error(), timeTaken: requiredTime}
-		return
-	}
-	defer res.Body.Close()
-	channel <- response{Website: website, Status: res.Status, result: string(body), timeTaken: requiredTime}
+func init() {
+	register("concurrency", "Goroutines, wait groups, channels and select", concurrencyLesson)
+	skipGolden("concurrency", "fetches live websites, so its output changes on every run")
 }
 
-// func main() {
+func concurrencyLesson(w io.Writer) {
 	// **Concurrency**
 	// Go has built-in support for concurrent programming using goroutines, wait groups and channels.
 	// Channels are used to communicate between goroutines and wait groups are used to wait for multiple goroutines to finish.
@@ -45,73 +26,113 @@ func request(website string, channel chan response, wg *sync.WaitGroup) {
 	// Buffered channels allow sending and receiving without blocking until the buffer is full.
 	// Unbuffered channels block until both sender and receiver are ready.
 	// Buffered channels can be created by passing a capacity to the `make` function.
-	channel := make(chan response) // Create a channel of type int, unbuffered by default and channel is a pointer
-	var wg sync.WaitGroup          // Create a wait group to wait for goroutines to finish
+	// The bare pattern is a goroutine per website, a wait group and an unbuffered channel (see request(website, channel, &wg) in the leaks lesson).
+	// However nothing can stop its `http.Get`, and a goroutine blocks forever sending on the unbuffered channel once the receiver has given up: it is leaked.
+	// A `context.Context` carries a deadline and a cancellation signal across goroutines, and every request made with it is aborted when it is done.
+	// Starting a goroutine per website is fine for a handful of websites, but thousands of them would open thousands of connections at once.
+	// Workers bounds the number of concurrent requests using a worker pool (see the pool package), the other websites wait in a queue.
+	fetcher := fetch.Fetcher{
+		Timeout: 10 * time.Second, // Deadline for every single request
+		Workers: 4,
+		// Retry the requests failing without a response or with a temporary status (429, 502, 503, 504), waiting longer after every attempt.
+		Retry: fetch.RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond},
+		// Every host gets a token bucket of 2 requests per second with a burst of 2, so a long list of URLs of the same website doesn't hammer it.
+		// The websites below are all different, so the limit doesn't slow them down (see the crawler lesson for the token bucket itself).
+		Limiter: &ratelimit.Limiter{PerHost: ratelimit.Limit{Rate: 2, Burst: 2}},
+		// The bodies are read as a stream into a SHA-256 hash, keeping only their size and hash instead of whole pages in memory.
+		// At most MaxBodySize bytes are read, a larger body is reported as Truncated.
+		MaxBodySize: 1 << 20,
+		DiscardBody: true,
+	}
 
 	websiteList := []string{"https://pkg.go.dev", "https://google.com", "https://github.com/ISanviI", "https://stackoverflow.com", "https://reddit.com"}
 
-	totalTime := int64(0)
+	// Overall deadline, `cancel` should always be called to release the context's resources (hence `defer`).
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Summing the latencies of concurrent requests doesn't tell how long they took, as they overlap: the wall clock time from start to end does.
+	var concLatencies []time.Duration
 	goRuntime := time.Now()
-	for i := 0; i < len(websiteList); i++ {
-		wg.Add(1)
-		go request(websiteList[i], channel, &wg)
-		// One could also receive message from channel here if it is unbuffered.
-	}
+	// FetchAll hands the websites to the workers and returns a channel buffered for all their responses, so no goroutine ever blocks on sending.
+	// One could also receive message from channel here if it is unbuffered.
+	channel := fetcher.FetchAll(ctx, websiteList)
 	goEnd := time.Since(goRuntime).Milliseconds()
-	fmt.Printf("Goroutines started at: %s and ended in %d ms.\n", goRuntime.Format(time.RFC3339), goEnd)
+	fmt.Fprintf(w, "Goroutines started at: %s and ended in %d ms.\n", goRuntime.Format(time.RFC3339), goEnd)
 
 loop:
 	for {
 		// `select` is used to wait on multiple channel operations. It will block until one of the cases can proceed.
 		// It is similar to `switch` but for channels.
 		select {
-		case msg := <-channel: // receive from data channel
-			totalTime += msg.timeTaken
-			fmt.Printf("Website: %s, Status: %s, Time Taken: %d ms\n", msg.Website, msg.Status, msg.timeTaken)
+		case msg, ok := <-channel: // receive from data channel
+			if !ok {
+				// FetchAll closes the channel once every goroutine has sent its response.
+				break loop
+			}
+			concLatencies = append(concLatencies, msg.Elapsed)
+			if msg.StatusCode == 0 {
+				// No response at all, msg.Kind tells whether it was DNS, a timeout, TLS, etc.
+				fmt.Fprintf(w, "Website: %s, Error (%s): %v, Time Taken: %d ms, Attempts: %d\n", msg.Website, msg.Kind, msg.Err, msg.Elapsed.Milliseconds(), len(msg.Attempts))
+				continue
+			}
+			fmt.Fprintf(w, "Website: %s, Status: %s, Size: %d bytes (truncated: %t), Time Taken: %d ms, Attempts: %d\n", msg.Website, msg.Status, msg.BodySize, msg.Truncated, msg.Elapsed.Milliseconds(), len(msg.Attempts))
+			// Where the time went: a slow DNS or TLS handshake needs a different fix than a slow server (wait) or a large page (transfer).
+			fmt.Fprintf(w, "    Phases: %s\n", msg.Phases)
 
 		case <-time.After(5 * time.Second): // wait for 5 sec inactivity
-			fmt.Println("no more messages, exiting...")
+			fmt.Fprintln(w, "no more messages, exiting...")
+			// Cancelling the context aborts the requests still in flight, their goroutines then send into the buffer and return.
+			cancel()
 			break loop
 
 			// Including a default here to avoid blocking if no messages are available, however in this case if we also add default, the inactivity case would never be executed.
 		}
 	}
-	fmt.Printf("\nTotal time taken for all websites with go runtime: %d ms\n", totalTime)
-
-	// If the channel had been closed by sender after sending all messages:
-	// msg, ok := <- channel {
-	// 	if !ok {
-	// 		fmt.Println("Channel is closed, no more messages will be received.")
-	// 		break
-	// 	}
-	// 	totalTime += msg.timeTaken
+	concWall := time.Since(goRuntime)
+
+	// As the channel is closed by the sender after sending all messages, it could also be received as:
+	// for msg := range channel {
+	// 	concLatencies = append(concLatencies, msg.Elapsed)
 	// }
 	// OR
-	// for msg := range channel {
-	// 	totalTime += msg.timeTaken
+	// msg, ok := <-channel
+	// if !ok {
+	// 	fmt.Fprintln(w, "Channel is closed, no more messages will be received.")
 	// }
 	// Sending on a closed channel causes panic!!
-	// If the channel is unbuffered, the sender should always close the channel after sending all messages.
-	// If the channel is buffered, it can be closed by the sender after sending all messages, but it is not necessary.
+	// Hence only the sender should close the channel, after sending all messages (FetchAll waits for all its goroutines first).
+	// A channel doesn't need to be closed at all if the receiver doesn't need to know that no more messages will be sent.
+	// The pipeline lesson (`learngo run pipeline`) puts these rules to work in stages connected by channels, which `learngo check` is built on.
 
-	allWebsTime := int64(0)
+	// A new context for the sequential run, as the one above may have been cancelled already.
+	seqCtx, seqCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer seqCancel()
+	var seqLatencies []time.Duration
+	seqStart := time.Now()
 	for i := 0; i < len(websiteList); i++ {
-		start := time.Now()
-		_, err := http.Get(websiteList[i])
-		if err != nil {
-			fmt.Printf("Error fetching %s: %s\n", websiteList[i], err.Error())
-			continue
+		msg := fetcher.Fetch(seqCtx, websiteList[i])
+		// Failed requests took time too, so they are part of the statistics.
+		seqLatencies = append(seqLatencies, msg.Elapsed)
+		if !msg.OK() {
+			fmt.Fprintf(w, "Error fetching %s (%s): %v\n", websiteList[i], msg.Kind, msg.Err)
 		}
-		allWebsTime += time.Since(start).Milliseconds()
 	}
-	fmt.Printf("\nTotal time taken for all websites without go runtime: %d ms\n", allWebsTime)
+	seqWall := time.Since(seqStart)
 
-	defer close(channel) // The sender should always close the channel
-	wg.Wait()            // Wait for all goroutines to finish
+	// Here the wall clock time is (about) the sum of the latencies, while it is closer to the slowest request with goroutines.
+	conc, seq := stats.Summarize(concLatencies, concWall), stats.Summarize(seqLatencies, seqWall)
+	fmt.Fprintln(w)
+	conc.Write(w, "With go runtime (goroutines)")
+	stats.Histogram(w, concLatencies, 5, 30)
+	seq.Write(w, "Without go runtime (sequential)")
+	stats.Histogram(w, seqLatencies, 5, 30)
+	fmt.Fprintf(w, "\nSpeed-up of the goroutines: %.1fx (wall clock time %s vs %s)\n",
+		stats.Speedup(seq, conc), seqWall.Round(time.Millisecond), concWall.Round(time.Millisecond))
 }
 
 // Tickers in GO that return channels
-// 1. time.Tick() - Returns a channel that sends the current time at regular intervals.
+// 1. time.Tick() - Returns a channel that sends the current time at regular intervals. The monitor package (`learngo monitor`) re-checks every website on its own interval in this spirit.
 // 2. time.After() - Returns a channel that sends the current time after a specified duration.
 // 3. time.Sleep() - Pauses the current goroutine for a specified duration.
 
@@ -2,12 +2,23 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ISanviI/LearnGo/counter"
+	"github.com/ISanviI/LearnGo/golden"
+	"github.com/ISanviI/LearnGo/leak"
+	"github.com/ISanviI/LearnGo/lockcheck"
+	"github.com/ISanviI/LearnGo/shardmap"
 )
 
-func update1(id int, counter *int, mu *sync.Mutex, wg *sync.WaitGroup) {
+// The workers take a sync.Locker rather than a *sync.Mutex, so that they also run with the instrumented locks of the lockcheck package.
+
+func update1(w io.Writer, id int, counter *int, mu sync.Locker, wg *sync.WaitGroup) {
 	// Counter could be a closure too.
 	defer wg.Done()
 
@@ -15,7 +26,7 @@ func update1(id int, counter *int, mu *sync.Mutex, wg *sync.WaitGroup) {
 		// lock before updating shared var
 		mu.Lock()
 		*counter++
-		fmt.Printf("Worker %d incremented counter to %d\n", id, *counter)
+		fmt.Fprintf(w, "Worker %d incremented counter to %d\n", id, *counter)
 		mu.Unlock()
 
 		// simulate work
@@ -23,58 +34,170 @@ func update1(id int, counter *int, mu *sync.Mutex, wg *sync.WaitGroup) {
 	}
 }
 
-func update2(id int, squareSum *int, mu *sync.Mutex, wg *sync.WaitGroup) {
+func update2(w io.Writer, id int, squareSum *int, mu sync.Locker, wg *sync.WaitGroup) {
 	defer wg.Done()
 	mu.Lock()
 	*squareSum += id * id
 	mu.Unlock()
-	fmt.Printf("Worker %d is done\n", id)
+	fmt.Fprintf(w, "Worker %d is done\n", id)
 	time.Sleep(100 * time.Millisecond)
 }
 
-func reader(id int, c1, c2 *int, mu *sync.RWMutex, wg *sync.WaitGroup) {
+// reader reads both counters, so it holds both of the locks guarding them: the read locks of two RWMutexes (see sync.RWMutex.RLocker).
+// Reading c1 under a lock of its own while update1 writes it under mu1 would be a data race, a lock only protects what every goroutine accesses under it.
+// Holding two locks at once calls for an order: every goroutine takes mu1 before mu2, otherwise two of them could each hold one and wait for the other forever.
+func reader(w io.Writer, id int, c1, c2 *int, r1, r2 sync.Locker, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for i := 0; i < 2; i++ {
-		mu.RLock() // shared lock
-		fmt.Printf("(Reader %d) sees counters: c1=%d, c2=%d\n", id, *c1, *c2)
-		mu.RUnlock()
+		r1.Lock() // shared lock
+		r2.Lock()
+		fmt.Fprintf(w, "(Reader %d) sees counters: c1=%d, c2=%d\n", id, *c1, *c2)
+		r2.Unlock()
+		r1.Unlock()
 
 		time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
 	}
 }
 
-// func main() {
+func init() {
+	register("mutexes", "Mutexes and RWMutexes guarding shared state", mutexesLesson,
+		// Which worker gets the lock first, and what the readers see while the workers run, changes on every run.
+		// The final counters are still checked exactly.
+		golden.Replace(`Worker \d+ incremented`, "Worker N incremented"),
+		golden.Replace(`sees counters: c1=\d+, c2=\d+`, "sees counters: c1=N, c2=N"),
+		golden.SortRuns(`^(Worker|\(Reader)`),
+		golden.Replace(`(waited|held) \S+ in total \(p99 \S+\)`, "$1 D in total (p99 D)"), // in µs or ms depending on the load
+		golden.Durations,               // how long the lock held on purpose was held
+		golden.SortRuns(`^    mu\d: `), // the locks are sorted by their wait time
+	)
+}
+
+func mutexesLesson(w io.Writer) {
 	var (
 		counter1 int
-		mu1      sync.Mutex
+		mu1      sync.RWMutex // readers share it, a writer holds it alone
 		wg       sync.WaitGroup
-		mu2      sync.Mutex
+		mu2      sync.RWMutex
 		counter2 int
-		rmu      sync.RWMutex
 		rwg      sync.WaitGroup
 	)
+	// Every worker must have returned once the wait groups are done, a worker still blocked in Lock would be leaked (see the leaks lesson).
+	before := leak.Take()
 
 	// spawn multiple goroutines calling the same function
-	for w := 1; w <= 3; w++ {
+	for id := 1; id <= 3; id++ {
 		wg.Add(2)
-		go update1(w, &counter1, &mu1, &wg)
-		go update2(w, &counter2, &mu2, &wg)
+		go update1(w, id, &counter1, &mu1, &wg)
+		go update2(w, id, &counter2, &mu2, &wg)
 	}
 
 	for r := 1; r <= 3; r++ {
 		wg.Add(1)
-		go reader(r, &counter1, &counter2, &rmu, &wg)
+		go reader(w, r, &counter1, &counter2, mu1.RLocker(), mu2.RLocker(), &wg)
 	}
 
 	wg.Wait()
-	fmt.Println("All workers finished.")
+	fmt.Fprintln(w, "All workers finished.")
 	// Final values of counters
 	for r := 1; r <= 3; r++ {
 		rwg.Add(1)
-		go reader(r, &counter1, &counter2, &rmu, &rwg)
+		go reader(w, r, &counter1, &counter2, mu1.RLocker(), mu2.RLocker(), &rwg)
 	}
 	rwg.Wait()
 
-	fmt.Println("Final Counter:", counter1)
-	fmt.Println("Final Square Sum:", counter2)
+	fmt.Fprintln(w, "Final Counter:", counter1)
+	fmt.Fprintln(w, "Final Square Sum:", counter2)
+
+	// A raw *int next to a *sync.Mutex relies on every goroutine locking the right mutex, nothing stops one from forgetting.
+	// The counter package hides the value behind a Counter interface instead, implemented with a mutex, a RWMutex, atomics, a goroutine owning the value and shards.
+	// The workers don't know which one they increment, and `go test -bench Counter ./counter` tells which one is the fastest for a given load.
+	owner := counter.NewOwner()
+	for _, c := range []struct {
+		name string
+		c    counter.Counter
+	}{
+		{"mutex", &counter.Mutex{}}, {"rwmutex", &counter.RWMutex{}}, {"atomic", &counter.Atomic{}}, {"owner", owner}, {"sharded", counter.NewSharded(4)},
+	} {
+		var cwg sync.WaitGroup
+		for range 8 {
+			cwg.Add(1)
+			go func() {
+				defer cwg.Done()
+				for range 1000 {
+					c.c.Inc()
+				}
+			}()
+		}
+		cwg.Wait()
+		fmt.Fprintf(w, "%-8s counter incremented 1000 times by 8 goroutines: %d\n", c.name, c.c.Value())
+	}
+	// The owner's goroutine runs until closed, it would be leaked otherwise.
+	owner.Close()
+
+	// The readers above share an RWMutex over two ints, shardmap.ConcurrentMap applies the same pattern to a whole map.
+	// The map is split into shards with an RWMutex each, so goroutines using keys of different shards don't wait for each other.
+	// Compute does the read-modify-write of a count with the shard locked, a Get followed by a Set could lose increments.
+	words := shardmap.New[string, int](8, shardmap.String)
+	var mwg sync.WaitGroup
+	for _, line := range []string{"go is fun", "locks are fun", "go go go"} {
+		mwg.Add(1)
+		go func() {
+			defer mwg.Done()
+			for _, word := range strings.Fields(line) {
+				words.Compute(word, func(n int, _ bool) (int, bool) { return n + 1, true })
+			}
+		}()
+	}
+	mwg.Wait()
+	var counts []string
+	words.Range(func(word string, n int) bool {
+		counts = append(counts, fmt.Sprintf("%s=%d", word, n))
+		return true
+	})
+	slices.Sort(counts) // Range visits the keys in no particular order
+	fmt.Fprintln(w, "Word counts:", strings.Join(counts, " "))
+
+	// A lock-order inversion only deadlocks when the timing is just wrong, so it can pass every test and hang in production.
+	// The lockcheck package predicts it instead: its Mutex and RWMutex record which locks every goroutine holds when it takes another one,
+	// and a cycle in the graph of those "held -> taken" edges is a potential deadlock, reported even though nothing deadlocked (yet).
+	// RecordTimes also measures how long the workers wait for every lock and how long they hold it.
+	detector := &lockcheck.Detector{HoldThreshold: 50 * time.Millisecond, RecordTimes: true}
+	lmu1 := &lockcheck.RWMutex{Name: "mu1", Detector: detector}
+	lmu2 := &lockcheck.RWMutex{Name: "mu2", Detector: detector}
+	var c1, c2 int
+	var lwg sync.WaitGroup
+	for id := 1; id <= 3; id++ {
+		lwg.Add(3)
+		go update1(io.Discard, id, &c1, lmu1, &lwg)
+		go update2(io.Discard, id, &c2, lmu2, &lwg)
+		go reader(io.Discard, id, &c1, &c2, lmu1.RLocker(), lmu2.RLocker(), &lwg)
+	}
+	lwg.Wait()
+	fmt.Fprintln(w, "The workers with instrumented locks:", detector.Check())
+	// A reader taking mu2 before mu1 can't deadlock with the other readers, as they all share the read locks: it isn't reported.
+	lwg.Add(1)
+	reader(io.Discard, 4, &c1, &c2, lmu2.RLocker(), lmu1.RLocker(), &lwg)
+	fmt.Fprintln(w, "After a reader taking mu2 before mu1:", detector.Check())
+	// A writer taking mu2 before mu1 can: it could hold mu2 while a reader holds mu1, each waiting for the other forever.
+	// It doesn't deadlock when it runs alone, but it is reported.
+	lmu2.Lock()
+	lmu1.Lock()
+	lmu1.Unlock()
+	lmu2.Unlock()
+	// So is a worker holding a lock while it sleeps, which makes every other worker wait.
+	lmu1.Lock()
+	time.Sleep(60 * time.Millisecond)
+	lmu1.Unlock()
+	for _, r := range detector.Reports() {
+		fmt.Fprintln(w, "   ", r)
+	}
+	// The workers sleep between their locks, so they rarely wait: mu1 is mostly held by the sleep above.
+	// A lock waited for much longer than it is held is contended, many goroutines want it at once.
+	// `learngo run -mutexprofile mutex.pprof mutexes` records where the goroutines waited using the runtime's own mutex profile, see `go tool pprof mutex.pprof`.
+	fmt.Fprintln(w, "Lock contention:")
+	for _, c := range detector.Contention() {
+		fmt.Fprintf(w, "    %s: taken %d times, waited %s in total (p99 %s), held %s in total (p99 %s)\n",
+			c.Name, c.Waits.Count, c.Waits.Sum, c.Waits.P99, c.Holds.Sum, c.Holds.P99)
+	}
+	fmt.Fprintln(w, "Goroutines leaked:", len(before.Leaked(leak.DefaultWait)))
 }
@@ -2,58 +2,41 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"time"
-)
-
-type cancatable interface {
-	String() string
-}
-
-type comparable interface {
-	~string | ~int | ~float64
-}
 
-// Parameterized interfaces - Life
-// Individual satisfies ProperNoun because it has Name() string.
-// Greeter satisfies Life[Individual] because it implements Action(Individual).
-type Life[T ProperNoun] interface {
-	Action(T)
-}
-type ProperNoun interface {
-	Name() string
-}
-type Individual struct {
-	name      string
-	birthYear int
-}
-
-func (p Individual) Name() string {
-	return p.name
-}
+	"github.com/ISanviI/LearnGo/generics"
+	"github.com/ISanviI/LearnGo/golden"
+)
 
-type CalAge struct {
-	age int
-}
+// The constraints, the parameterized Life interface and the generic functions used below live in the generics package.
 
-func (a *CalAge) Action(p Individual) {
-	a.age = time.Now().Year() - p.birthYear
+func init() {
+	register("generics", "Type parameters and constraints", genericsLesson,
+		golden.Replace(`age is: \d+`, "age is: N"), // depends on the current year
+	)
 }
 
-// func main() {
+func genericsLesson(w io.Writer) {
 	// Generics allow you to write functions and data structures that can work with any data type.
 	// This is useful for creating reusable code that can handle different types without duplication.
 	// Very similar to templates in C++ or generics in Java.
 
-}
+	// The type parameter is inferred from the argument, generics.Print[int](w, 42) is the explicit form.
+	generics.Print(w, 42)
+	generics.Print(w, "Hello, Generics!")
 
-func Print[T any](value T) {
-	fmt.Printf("%v\n", value)
-}
+	// time.Duration has a String() method, so it satisfies the Concatable constraint.
+	fmt.Fprintln(w, "Concatenated durations:", generics.Concat(time.Second, 90*time.Minute))
 
-func concat[T cancatable](a, b T) string {
-	return a.String() + b.String()
-}
+	// The `~` in the Comparable constraint also allows types whose underlying type is string, int or float64.
+	type Celsius float64
+	fmt.Fprintln(w, "Compare ints:", generics.Compare(3, 3))
+	fmt.Fprintln(w, "Compare strings:", generics.Compare("go", "Go"))
+	fmt.Fprintln(w, "Compare Celsius:", generics.Compare(Celsius(36.6), Celsius(37)))
 
-func compare[T comparable](a, b T) bool {
-	return a == b
+	// CalAge satisfies Life[Individual], so it can be used wherever that instantiated interface is expected.
+	var life generics.Life[generics.Individual] = &generics.CalAge{}
+	life.Action(generics.NewIndividual("Gopher", 2009))
+	fmt.Fprintln(w, "Gopher's age is:", life.(*generics.CalAge).Age())
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+	"github.com/ISanviI/LearnGo/crawl"
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/golden"
+	"github.com/ISanviI/LearnGo/ratelimit"
+)
+
+func init() {
+	register("crawler", "A concurrent web crawler: visited set, depth limit, politeness delay and rate limits", crawlerLesson,
+		golden.Replace(`http://127\.0\.0\.1:\d+`, "http://SITE"), // the test server listens on a random port
+	)
+}
+
+// crawlerSite is a small website served by an httptest.Server, so that the lesson doesn't depend on the network.
+var crawlerSite = map[string]string{
+	"/": `<html><body>
+		<a href="/about">About</a> <a href="blog">Blog</a> <a href="/blog#top">Blog again</a>
+		<a href="/missing">Broken link</a> <a href="https://example.com/">Elsewhere</a> <a href="mailto:me@example.com">Mail</a>
+		<!-- <a href="/secret">commented out</a> -->
+		<script>document.write('<a href="/from-script">')</script>
+	</body></html>`,
+	"/about": `<a href="/">Home</a> <a href="/team">Team</a>`,
+	"/team":  `<a href="/">Home</a> <a href='/about'>About</a>`,
+	"/blog": `<base href="/blog/">
+		<a href="post-1">First post</a> <a href="post-2">Second post</a>`,
+	"/blog/post-1":  `<a href="/blog/post-2">Next</a> <a href="/blog/archive">Archive</a>`,
+	"/blog/post-2":  `<a href="/blog/post-1">Previous</a> <a href="/blog/archive">Archive</a>`,
+	"/blog/archive": `<a href="/blog/post-1">First post</a>`,
+}
+
+func crawlerLesson(w io.Writer) {
+	// A crawler fetches a page, finds its links and fetches them in turn: every page found is more work to do.
+	// Unlike FetchAll, the list of URLs isn't known upfront, so the goroutines add work themselves and a sync.WaitGroup tells when there is none left.
+	// Pages link to each other (every page links back home), so a visited set makes sure that every page is fetched once.
+	// It is shared by all the goroutines, so it is guarded by a mutex, see crawl.Visited.
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		page, ok := crawlerSite[r.URL.Path]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(rw, page)
+	}))
+	defer srv.Close()
+
+	starts := &startRecorder{}
+	c := crawl.Crawler{
+		Fetcher:  &fetch.Fetcher{Timeout: 5 * time.Second, Observer: starts},
+		MaxDepth: 2, // the archive is 3 links away from home, so it is found but not fetched
+		Workers:  4,
+		// Fetching concurrently from a single website could overload it, the politeness delay spaces the requests to every host.
+		Delay: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	g, err := c.Crawl(ctx, srv.URL)
+	if err != nil {
+		fmt.Fprintln(w, "Crawl failed:", err)
+		return
+	}
+
+	// The pages are found in a different order on every run, the graph is sorted by depth and URL.
+	fmt.Fprintf(w, "Site graph of %s:\n", g.Start)
+	g.WriteText(w)
+
+	mu.Lock()
+	defer mu.Unlock()
+	twice := 0
+	for _, n := range requests {
+		if n > 1 {
+			twice++
+		}
+	}
+	fmt.Fprintf(w, "\nPages fetched: %d, pages fetched more than once: %d\n", len(requests), twice)
+	// The requests start one at a time, at least Delay apart, even with 4 workers, as they all go to the same host.
+	// A timer can fire a little late and bring the next request closer to it, but the n requests still span at least n-1 delays.
+	n, span := starts.span()
+	fmt.Fprintf(w, "%d requests spread over at least %d x %s: %t\n", n, n-1, c.Delay, span >= time.Duration(n-1)*c.Delay*9/10)
+
+	// A politeness delay lets a request through every Delay and no more. A token bucket (see the ratelimit package) also allows bursts:
+	// it holds up to Burst tokens and is refilled at Rate tokens per second, every request takes a token.
+	// Set as the Limiter of a fetch.Fetcher, it is waited on before every request.
+	// Its clock is a fake one (see the clock package) which only moves when Advance is called, so the output is the same on every run.
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := &ratelimit.Limiter{PerHost: ratelimit.Limit{Rate: 2, Burst: 3}, Clock: fake}
+	fmt.Fprintln(w, "\nToken bucket of 2 requests per second with a burst of 3:")
+	for i := 1; i <= 4; i++ {
+		fmt.Fprintf(w, "Request %d to a.example allowed: %t\n", i, limiter.Allow("a.example"))
+	}
+	fmt.Fprintln(w, "Request to b.example allowed:", limiter.Allow("b.example")) // every host has its own bucket
+	// Half a second at 2 tokens per second refills a token.
+	fake.Advance(500 * time.Millisecond)
+	fmt.Fprintln(w, "After 500ms, request 5 to a.example allowed:", limiter.Allow("a.example"))
+
+	// Wait blocks until the token is there, instead of failing.
+	done := make(chan error)
+	go func() { done <- limiter.Wait(ctx, "a.example") }()
+	fake.BlockUntil(1) // the goroutine is now waiting on the clock
+	fmt.Fprintln(w, "Goroutines waiting for a token:", fake.Waiters())
+	fake.Advance(500 * time.Millisecond)
+	fmt.Fprintln(w, "After another 500ms, Wait returned:", <-done)
+
+	// There is no point in waiting for a token that comes after the deadline, Wait fails straight away then.
+	short, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelShort()
+	fmt.Fprintln(w, "Wait with 100ms left:", limiter.Wait(short, "a.example"))
+}
+
+// startRecorder is a fetch.Observer recording when every request starts.
+type startRecorder struct {
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (s *startRecorder) RequestStarted(string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.starts = append(s.starts, time.Now())
+}
+
+func (s *startRecorder) RequestDone(fetch.Result)    {}
+func (s *startRecorder) RequestSkipped(fetch.Result) {}
+
+// span returns the number of requests and the time between the start of the first and the last one.
+func (s *startRecorder) span() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.starts) == 0 {
+		return 0, 0
+	}
+	return len(s.starts), s.starts[len(s.starts)-1].Sub(s.starts[0])
+}
@@ -0,0 +1,24 @@
+package calc
+
+import "fmt"
+
+// **Error Handling** - Go uses multiple return values to handle errors.
+
+// Divide returns a / b, or a DivisionError when b is zero.
+func Divide(a, b int) (int, error) {
+	if b == 0 {
+		// return 0, fmt.Errorf("DivisionError - %w", errors.New("Division by zero is not allowed"))
+		// Alternatively, more correct way while using the Error interface is by creating a custom error struct type:
+		return 0, DivisionError{Message: "Division by zero is not allowed."}
+	}
+	return a / b, nil
+}
+
+// DivisionError is a custom error type
+type DivisionError struct {
+	Message string
+}
+
+func (e DivisionError) Error() string {
+	return fmt.Sprintf("DivisionError: %s", e.Message)
+}
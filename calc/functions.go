@@ -0,0 +1,55 @@
+// Package calc holds the small arithmetic helpers used by the functions and errors lessons.
+package calc
+
+// Note that functions by default in GO are 'call by value' and not 'call by reference' unless you explicitly pass address using `&` like in C/C++.
+
+// Add returns the sum of a and b.
+func Add(a int, b int) int {
+	return a + b
+}
+
+// IgnoreReturn returns two values so that the caller can ignore one of them using `_`.
+func IgnoreReturn() (string, int) {
+	return "Hello", 5
+}
+
+// NakedReturn returns the sum and the difference of a and b.
+func NakedReturn(a, b int) (x, y int) {
+	x = a + b
+	y = a - b
+	return // naked return, no need to specify return values, returns x and y automatically (called as implicit), not preferred.
+}
+
+// Sum is a variadic function adding all of its arguments.
+func Sum(numbers ...int) int {
+	// ... only means that the function can take a variable number of arguments but inside function it can be treated as a slice.
+	total := 0
+	for _, num := range numbers {
+		total += num
+	}
+	return total
+}
+
+// HigherOrder calls fn with a and b.
+// Higher order functions are used in HTTP API handlers, Pub/Sub handlers (see pubsub.Broker.Handle), onClick callbacks, etc.
+func HigherOrder(fn func(int, int) int, a, b int) int {
+	return fn(a, b) // Calls the passed function with a and b as arguments
+}
+
+// CurriedDouble returns a function calling f with the same argument twice.
+// Currying is used mostly in middleware functions in web frameworks, like Express.js in Node.js.
+func CurriedDouble(f func(int, int) int) func(int) int {
+	return func(x int) int {
+		return f(x, x) // Returns a function (called currying)
+	}
+}
+
+// MakeCounter returns a closure adding x to a captured count and returning the new count.
+// Closures are functions that capture the variables from their surrounding context.
+func MakeCounter() func(x int) int {
+	count := 0               // This variable is captured by the closure
+	return func(x int) int { // If it hadn't been a function, it would have been a normal variable and not a closure.
+		count += x
+		return count
+	}
+}
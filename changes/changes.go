@@ -0,0 +1,165 @@
+// Package changes detects which websites changed between two runs of the checker.
+// It keeps the validators (ETag, Last-Modified) and the content hash of every website in a JSON file,
+// sends them back as a conditional request on the next run, and compares the new result with the stored one.
+package changes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// Change is what happened to a website since the previous run.
+type Change string
+
+const (
+	New         Change = "new"         // not checked before
+	Changed     Change = "changed"     // the content differs from the previous run
+	Unchanged   Change = "unchanged"   // the server answered 304 Not Modified, or the content hash is the same
+	Disappeared Change = "disappeared" // the website was there and now answers 404 or 410, or its host is gone from the DNS
+	Failed      Change = "failed"      // the check failed in a way which tells nothing about the content, e.g. a timeout
+)
+
+// Entry is what is remembered about a website.
+type Entry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentHash  string    `json:"content_hash,omitempty"`
+	Status       int       `json:"status"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// Store holds an Entry per website, it is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// Load reads a Store saved by Save, a missing file gives an empty Store as on the very first run.
+func Load(path string) (*Store, error) {
+	s := &Store{entries: map[string]Entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("changes: reading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the Store to path as a JSON object keyed by website.
+// It writes a temporary file first and renames it, so that an interrupted run never leaves a half written file behind.
+func (s *Store) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Entry returns the stored Entry of website.
+func (s *Store) Entry(website string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[website]
+	return e, ok
+}
+
+// Header returns the conditional request headers of website, to be used as fetch.Fetcher.RequestHeader.
+// The server answers 304 Not Modified without a body when the page still has the stored ETag or hasn't been modified since Last-Modified.
+func (s *Store) Header(website string) http.Header {
+	e, ok := s.Entry(website)
+	if !ok || !hasContent(e.Status) {
+		return nil
+	}
+	h := http.Header{}
+	if e.ETag != "" {
+		h.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		h.Set("If-Modified-Since", e.LastModified)
+	}
+	return h
+}
+
+// Update compares res with the stored Entry of its website, stores what is needed for the next run and returns the Change.
+func (s *Store) Update(res fetch.Result, at time.Time) Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, known := s.entries[res.Website]
+
+	switch {
+	case res.StatusCode == http.StatusNotModified:
+		// The body is empty, the stored hash is still the one of the content.
+		prev.Status, prev.CheckedAt = res.StatusCode, at
+		if etag := res.Header.Get("ETag"); etag != "" {
+			prev.ETag = etag
+		}
+		s.entries[res.Website] = prev
+		return Unchanged
+
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone || res.Kind == fetch.KindDNS:
+		s.entries[res.Website] = Entry{Status: res.StatusCode, CheckedAt: at}
+		if known && !gone(prev.Status) {
+			return Disappeared
+		}
+		if known {
+			return Unchanged // already gone on the previous run
+		}
+		return New
+
+	case res.Err != nil:
+		return Failed // the stored Entry is kept, as nothing new is known about the content
+	}
+
+	s.entries[res.Website] = Entry{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		ContentHash:  res.ContentHash,
+		Status:       res.StatusCode,
+		CheckedAt:    at,
+	}
+	switch {
+	case !known:
+		return New
+	case prev.ContentHash != res.ContentHash:
+		return Changed
+	}
+	return Unchanged
+}
+
+// hasContent reports whether a stored status comes with content, which the validators are about.
+func hasContent(status int) bool {
+	return 200 <= status && status < 300 || status == http.StatusNotModified
+}
+
+// gone reports whether a stored status means the website had already disappeared, 0 being a DNS failure.
+func gone(status int) bool {
+	return status == 0 || status == http.StatusNotFound || status == http.StatusGone
+}
@@ -0,0 +1,158 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// site is a website whose content can be changed between runs, with or without validators.
+type site struct {
+	mu         sync.Mutex
+	version    int
+	validators bool
+	status     int // answered instead of the content if not 0
+	notChanged int // number of 304 answers
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	if s.validators {
+		etag := fmt.Sprintf(`"v%d"`, s.version)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.notChanged++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	fmt.Fprintf(w, "content version %d", s.version)
+}
+
+func (s *site) set(f func(s *site)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s)
+}
+
+// runs checks the website once per call, as a run of the checker would, and returns the Change.
+func runs(t *testing.T, s *site) func() Change {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	store, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fetch.Fetcher{Client: srv.Client(), RequestHeader: store.Header}
+	return func() Change {
+		res := f.Fetch(context.Background(), srv.URL)
+		return store.Update(res, time.Now())
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := &site{validators: true}
+	run := runs(t, s)
+	want := []struct {
+		change     Change
+		notChanged int
+	}{{New, 0}, {Unchanged, 1}, {Unchanged, 2}}
+	for i, w := range want {
+		got := run()
+		if got != w.change || s.notChanged != w.notChanged {
+			t.Fatalf("run %d: %s with %d 304s, want %s with %d", i+1, got, s.notChanged, w.change, w.notChanged)
+		}
+	}
+	s.set(func(s *site) { s.version++ })
+	if got := run(); got != Changed {
+		t.Fatalf("after a change of the ETag: %s, want changed", got)
+	}
+	if got := run(); got != Unchanged || s.notChanged != 3 {
+		t.Errorf("after the change: %s with %d 304s, want the new ETag to be sent back", got, s.notChanged)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	// Without validators every request gets the whole body, only its hash tells whether it changed.
+	s := &site{}
+	run := runs(t, s)
+	for i, want := range []Change{New, Unchanged} {
+		if got := run(); got != want {
+			t.Fatalf("run %d: %s, want %s", i+1, got, want)
+		}
+	}
+	s.set(func(s *site) { s.version++ })
+	if got := run(); got != Changed {
+		t.Errorf("after a change of the body: %s, want changed", got)
+	}
+	if got := run(); got != Unchanged {
+		t.Errorf("after the change: %s, want unchanged", got)
+	}
+	if s.notChanged != 0 {
+		t.Errorf("%d 304s without validators", s.notChanged)
+	}
+}
+
+func TestDisappeared(t *testing.T) {
+	s := &site{validators: true}
+	run := runs(t, s)
+	run()
+	s.set(func(s *site) { s.status = http.StatusGone })
+	if got := run(); got != Disappeared {
+		t.Errorf("answering 410: %s, want disappeared", got)
+	}
+	if got := run(); got != Unchanged {
+		t.Errorf("still answering 410: %s, want unchanged", got)
+	}
+	s.set(func(s *site) { s.status = http.StatusServiceUnavailable })
+	if got := run(); got != Failed {
+		t.Errorf("answering 503: %s, want failed", got)
+	}
+	// The validators of a gone website aren't sent back, the content comes back in full.
+	s.set(func(s *site) { s.status = 0 })
+	if got := run(); got != Changed || s.notChanged != 0 {
+		t.Errorf("back: %s with %d 304s, want changed without a conditional request", got, s.notChanged)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	header := http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Mon, 01 Jan 2024 00:00:00 GMT"}}
+	s.Update(fetch.Result{Website: "https://example.com", StatusCode: 200, Header: header, ContentHash: "abc"}, at)
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{ETag: `"v1"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT", ContentHash: "abc", Status: 200, CheckedAt: at}
+	if got, ok := loaded.Entry("https://example.com"); !ok || got != want {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+	h := loaded.Header("https://example.com")
+	if h.Get("If-None-Match") != `"v1"` || h.Get("If-Modified-Since") != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Errorf("Header = %v", h)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ISanviI/LearnGo/changes"
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/metrics"
+	"github.com/ISanviI/LearnGo/pipeline"
+	"github.com/ISanviI/LearnGo/ratelimit"
+	"github.com/ISanviI/LearnGo/report"
+)
+
+// runCheck is the website checker of the concurrency lesson as a command: it reads the URLs from a file (or stdin), checks them all and writes the reports.
+// It fails when a website couldn't be fetched, so that CI can use it directly.
+func runCheck(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	urlsPath := fs.String("urls", "-", "file with one URL per line (`#` starts a comment), - for stdin")
+	jsonPath := fs.String("json", "", "write a JSON Lines report to this file, - for stdout")
+	csvPath := fs.String("csv", "", "write a CSV report to this file, - for stdout")
+	workers := fs.Int("workers", 16, "number of concurrent requests")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of every request")
+	attempts := fs.Int("attempts", 3, "number of attempts for failing requests")
+	rate := fs.Float64("rate", 0, "maximum requests per second to every host, 0 for no limit")
+	globalRate := fs.Float64("global-rate", 0, "maximum requests per second overall, 0 for no limit")
+	burst := fs.Int("burst", 1, "number of requests let through at once before -rate and -global-rate apply")
+	maxBody := fs.Int64("max-body", fetch.DefaultMaxBodySize, "number of bytes read from a body at most, the rest is reported as truncated, negative for no limit")
+	maxRedirects := fs.Int("max-redirects", 10, "number of redirects followed at most, negative to not follow any")
+	certWarnDays := fs.Int("cert-warn-days", 14, "warn about certificates expiring within this many days, 0 never")
+	breaker := fs.Int("breaker", 0, "consecutive failures of a host after which its requests fail without being made, 0 for no circuit breaker")
+	cooldown := fs.Duration("cooldown", 30*time.Second, "time before probing a host whose circuit is open")
+	statePath := fs.String("state", "", "remember the websites in this JSON file, send conditional requests and report the changes since the previous run")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics on http://<addr>/metrics while checking, e.g. localhost:9090")
+	fs.Parse(args)
+
+	urls, err := readURLs(*urlsPath)
+	if err != nil {
+		return fmt.Errorf("check: %w", err)
+	}
+
+	var writers []report.Writer
+	// The report files are closed (and their errors checked) once written, this only closes them when returning early.
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	stdout := w
+	toStdout := 0
+	for _, out := range []struct {
+		path string
+		new  func(io.Writer) report.Writer
+	}{
+		{*jsonPath, func(w io.Writer) report.Writer { return report.NewJSONLines(w) }},
+		{*csvPath, func(w io.Writer) report.Writer { return report.NewCSV(w) }},
+	} {
+		switch out.path {
+		case "":
+			continue
+		case "-":
+			// Two reports in different formats mixed on stdout couldn't be parsed.
+			if toStdout++; toStdout > 1 {
+				return fmt.Errorf("check: only one of -json and -csv can write to stdout")
+			}
+			writers = append(writers, out.new(stdout))
+			// The report owns stdout, the human readable lines go to stderr then.
+			w = os.Stderr
+		default:
+			f, err := os.Create(out.path)
+			if err != nil {
+				return fmt.Errorf("check: %w", err)
+			}
+			files = append(files, f)
+			writers = append(writers, out.new(f))
+		}
+	}
+
+	fetcher := fetch.Fetcher{
+		Timeout: *timeout,
+		Retry:   fetch.RetryPolicy{MaxAttempts: *attempts},
+		// The reports only need the size and the hash of the bodies, not the bodies themselves.
+		MaxBodySize:       *maxBody,
+		DiscardBody:       true,
+		MaxRedirects:      *maxRedirects,
+		CertExpiryWarning: time.Duration(*certWarnDays) * 24 * time.Hour,
+	}
+	if *rate > 0 || *globalRate > 0 {
+		fetcher.Limiter = &ratelimit.Limiter{
+			PerHost: ratelimit.Limit{Rate: *rate, Burst: *burst},
+			Global:  ratelimit.Limit{Rate: *globalRate, Burst: *burst},
+		}
+	}
+	if *breaker > 0 {
+		fetcher.Breaker = &fetch.Breaker{Threshold: *breaker, Cooldown: *cooldown}
+	}
+	if *metricsAddr != "" {
+		reg := &metrics.Registry{}
+		fetcher.Observer = metrics.NewFetchMetrics(reg)
+		stop, err := serveMetrics(*metricsAddr, reg)
+		if err != nil {
+			return fmt.Errorf("check: %w", err)
+		}
+		defer stop()
+	}
+	var store *changes.Store
+	if *statePath != "" {
+		if store, err = changes.Load(*statePath); err != nil {
+			return fmt.Errorf("check: %w", err)
+		}
+		fetcher.RequestHeader = store.Header
+	}
+
+	// The checker is a pipeline (see the pipeline package): the URLs are fetched by *workers goroutines, compared with the previous run and written out.
+	// An error writing a report stops every stage, and so does Ctrl+C.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	p := pipeline.New(ctx)
+	type checked struct {
+		res    fetch.Result
+		at     time.Time
+		change changes.Change
+	}
+
+	toFetch := pipeline.FromSlice(p, 0, urls)
+	// Ordered, as reports in the same order as the list of URLs are easier to compare between runs.
+	fetched := pipeline.Map(p, toFetch, pipeline.Options{Workers: *workers, Buffer: *workers, Ordered: true},
+		func(ctx context.Context, url string) (fetch.Result, error) {
+			// A failed fetch is a result like any other, not an error stopping the pipeline.
+			return fetcher.Fetch(ctx, url), nil
+		})
+	compared := pipeline.Map(p, fetched, pipeline.Options{},
+		func(_ context.Context, res fetch.Result) (checked, error) {
+			c := checked{res: res, at: time.Now()}
+			if store != nil {
+				c.change = store.Update(res, c.at)
+			}
+			return c, nil
+		})
+
+	failed := 0
+	counts := map[changes.Change]int{}
+	pipeline.Drain(p, compared, func(_ context.Context, c checked) error {
+		res, note := c.res, ""
+		if store != nil {
+			counts[c.change]++
+			note = fmt.Sprintf(" [%s]", c.change)
+		}
+		if !res.OK() {
+			failed++
+			fmt.Fprintf(w, "FAIL %s (%s): %v%s\n", res.Website, res.Kind, res.Err, note)
+		} else {
+			truncated := ""
+			if res.Truncated {
+				truncated = fmt.Sprintf(" (body truncated to %d bytes)", res.BodySize)
+			}
+			fmt.Fprintf(w, "ok   %s %s in %s%s%s\n", res.Website, res.Status, res.Elapsed.Round(time.Millisecond), truncated, note)
+		}
+		for _, h := range res.Redirects {
+			fmt.Fprintf(w, "     redirected by %s (%s)\n", h.URL, h.Status)
+		}
+		for _, warning := range res.Warnings {
+			fmt.Fprintf(w, "WARN %s: %s\n", res.Website, warning)
+		}
+		rec := report.NewRecord(res, c.at)
+		rec.Change = string(c.change)
+		for _, rw := range writers {
+			if err := rw.Write(rec); err != nil {
+				return fmt.Errorf("writing report: %w", err)
+			}
+		}
+		return nil
+	})
+	// The reports and the state are still written when the pipeline stopped early, they hold the websites checked so far.
+	pipelineErr := p.Wait()
+
+	for _, rw := range writers {
+		if err := rw.Flush(); err != nil {
+			return fmt.Errorf("check: writing report: %w", err)
+		}
+	}
+	// Close can fail too, e.g. when the disk is full and the last writes only fail once flushed to it.
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("check: writing report: %w", err)
+		}
+	}
+	files = nil
+	if store != nil {
+		if err := store.Save(*statePath); err != nil {
+			return fmt.Errorf("check: saving state: %w", err)
+		}
+		fmt.Fprintf(w, "Since the previous run: %d new, %d changed, %d unchanged, %d disappeared, %d failed\n",
+			counts[changes.New], counts[changes.Changed], counts[changes.Unchanged], counts[changes.Disappeared], counts[changes.Failed])
+	}
+	if pipelineErr != nil {
+		return fmt.Errorf("check: %w", pipelineErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("check: %d of %d websites failed", failed, len(urls))
+	}
+	return nil
+}
+
+func readURLs(path string) ([]string, error) {
+	if path == "-" {
+		return report.ReadURLs(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return report.ReadURLs(f)
+}
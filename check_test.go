@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckReports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }))
+	defer srv.Close()
+	dir := t.TempDir()
+	urls := filepath.Join(dir, "urls.txt")
+	if err := os.WriteFile(urls, []byte(srv.URL+"/a\n# a comment\n"+srv.URL+"/b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout strings.Builder
+	csvPath := filepath.Join(dir, "report.csv")
+	if err := runCheck(&stdout, []string{"-urls", urls, "-json", "-", "-csv", csvPath}); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	// stdout only holds the JSON Lines report, the human readable lines went to stderr.
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], "{") {
+		t.Errorf("stdout = %q, want 2 JSON lines", stdout.String())
+	}
+	csv, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(csv)), "\n"); len(lines) != 3 {
+		t.Errorf("CSV report = %q, want a header and 2 rows", csv)
+	}
+}
+
+func TestCheckTwoReportsOnStdout(t *testing.T) {
+	var stdout strings.Builder
+	err := runCheck(&stdout, []string{"-urls", os.DevNull, "-json", "-", "-csv", "-"})
+	if err == nil || !strings.Contains(err.Error(), "stdout") {
+		t.Errorf("check with -json - -csv - = %v, want an error", err)
+	}
+}
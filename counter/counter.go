@@ -0,0 +1,147 @@
+// Package counter implements the same Counter several ways, to compare the ways of sharing state between goroutines:
+// a mutex, a read/write mutex, atomic operations, a goroutine owning the value and shards spreading the writes.
+// Which one is fastest depends on the number of goroutines and on how often the value is read, `go test -bench Counter ./counter` measures it.
+package counter
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is an integer incremented and read by many goroutines at once.
+type Counter interface {
+	Add(delta int64)
+	Inc()
+	Value() int64
+}
+
+// Mutex guards the value with a sync.Mutex, like counter1 in the mutexes lesson: readers wait for each other too.
+// Its zero value is ready to use.
+type Mutex struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *Mutex) Add(delta int64) {
+	c.mu.Lock()
+	c.n += delta
+	c.mu.Unlock()
+}
+
+func (c *Mutex) Inc() { c.Add(1) }
+
+func (c *Mutex) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// RWMutex guards the value with a sync.RWMutex, so readers only wait for writers.
+// Its zero value is ready to use.
+type RWMutex struct {
+	mu sync.RWMutex
+	n  int64
+}
+
+func (c *RWMutex) Add(delta int64) {
+	c.mu.Lock()
+	c.n += delta
+	c.mu.Unlock()
+}
+
+func (c *RWMutex) Inc() { c.Add(1) }
+
+func (c *RWMutex) Value() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.n
+}
+
+// Atomic updates the value with a single atomic instruction, without any lock.
+// Its zero value is ready to use.
+type Atomic struct {
+	n atomic.Int64
+}
+
+func (c *Atomic) Add(delta int64) { c.n.Add(delta) }
+func (c *Atomic) Inc()            { c.n.Add(1) }
+func (c *Atomic) Value() int64    { return c.n.Load() }
+
+// Owner keeps the value in a goroutine of its own, the other goroutines send it requests on a channel:
+// "Don't communicate by sharing memory, share memory by communicating." No lock is needed as only the owner touches the value.
+// Close must be called to stop the goroutine, the Owner can't be used after that.
+type Owner struct {
+	ops  chan int64
+	gets chan chan int64
+	done chan struct{}
+}
+
+// NewOwner starts the goroutine owning the value.
+func NewOwner() *Owner {
+	c := &Owner{ops: make(chan int64), gets: make(chan chan int64), done: make(chan struct{})}
+	go c.run()
+	return c
+}
+
+func (c *Owner) run() {
+	var n int64
+	for {
+		select {
+		case delta := <-c.ops:
+			n += delta
+		case reply := <-c.gets:
+			reply <- n
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Owner) Add(delta int64) { c.ops <- delta }
+func (c *Owner) Inc()            { c.ops <- 1 }
+
+func (c *Owner) Value() int64 {
+	reply := make(chan int64)
+	c.gets <- reply
+	return <-reply
+}
+
+// Close stops the goroutine owning the value.
+func (c *Owner) Close() { close(c.done) }
+
+// Sharded spreads the writes over several atomic counters, so that goroutines on different CPUs rarely write to the same cache line.
+// Value sums the shards, which makes reading slower, and the sum isn't a snapshot: the shards are read one after the other while writers go on.
+type Sharded struct {
+	shards []shard
+}
+
+// shard is padded to the size of a cache line, otherwise neighbouring shards would share a line and the CPUs would still fight over it ("false sharing").
+type shard struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// NewSharded returns a Counter with n shards, runtime.GOMAXPROCS(0) (one per CPU) if n < 1.
+func NewSharded(n int) *Sharded {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return &Sharded{shards: make([]shard, n)}
+}
+
+// Add adds to a random shard: Go doesn't tell a goroutine which CPU it runs on, and a random shard spreads the writes about as well.
+func (c *Sharded) Add(delta int64) {
+	c.shards[rand.IntN(len(c.shards))].n.Add(delta)
+}
+
+func (c *Sharded) Inc() { c.Add(1) }
+
+func (c *Sharded) Value() int64 {
+	var sum int64
+	for i := range c.shards {
+		sum += c.shards[i].n.Load()
+	}
+	return sum
+}
@@ -0,0 +1,106 @@
+package counter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+var kinds = []struct {
+	name string
+	new  func() (c Counter, close func())
+}{
+	{"mutex", func() (Counter, func()) { return &Mutex{}, func() {} }},
+	{"rwmutex", func() (Counter, func()) { return &RWMutex{}, func() {} }},
+	{"atomic", func() (Counter, func()) { return &Atomic{}, func() {} }},
+	{"owner", func() (Counter, func()) { c := NewOwner(); return c, c.Close }},
+	{"sharded", func() (Counter, func()) { return NewSharded(0), func() {} }},
+	{"sharded-1", func() (Counter, func()) { return NewSharded(1), func() {} }},
+}
+
+// TestConcurrentIncrements checks that no increment is lost, run it with -race to also check that the counters don't race.
+func TestConcurrentIncrements(t *testing.T) {
+	const goroutines, increments = 16, 2000
+	for _, kind := range kinds {
+		t.Run(kind.name, func(t *testing.T) {
+			defer leak.Verify(t)()
+			c, closeCounter := kind.new()
+			defer closeCounter()
+
+			var wg sync.WaitGroup
+			for g := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					last := int64(0)
+					for i := range increments {
+						if g%2 == 0 && i%2 == 0 {
+							c.Add(2)
+						} else {
+							c.Inc()
+						}
+						// Reading while the others write: the value only goes up.
+						if i%100 == 0 {
+							v := c.Value()
+							if v < last {
+								t.Errorf("Value went down from %d to %d", last, v)
+							}
+							last = v
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			// Half of the goroutines add 2 every other time.
+			want := int64(goroutines*increments + goroutines/2*increments/2)
+			if got := c.Value(); got != want {
+				t.Errorf("Value = %d after %d goroutines incremented it, want %d", got, goroutines, want)
+			}
+		})
+	}
+}
+
+// BenchmarkCounter runs every Counter with a few numbers of goroutines and shares of reads, so that the right one can be picked with data:
+//
+//	go test -bench 'Counter/.*/goroutines-64' ./counter
+//
+// Every op is an Inc or a Value, the b.N ops are split between the goroutines.
+func BenchmarkCounter(b *testing.B) {
+	for _, kind := range kinds {
+		for _, goroutines := range []int{1, 8, 64} {
+			for _, reads := range []int{0, 50, 90} {
+				b.Run(fmt.Sprintf("%s/goroutines-%d/reads-%d%%", kind.name, goroutines, reads), func(b *testing.B) {
+					c, closeCounter := kind.new()
+					defer closeCounter()
+					benchmarkCounter(b, c, goroutines, reads)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkCounter(b *testing.B, c Counter, goroutines, readPercent int) {
+	var wg sync.WaitGroup
+	b.ResetTimer()
+	for g := range goroutines {
+		// The first goroutines take the remainder of the division.
+		ops := b.N / goroutines
+		if g < b.N%goroutines {
+			ops++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ops {
+				if i%100 < readPercent {
+					c.Value()
+				} else {
+					c.Inc()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ISanviI/LearnGo/crawl"
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// runCrawl crawls a website from a start URL and writes its graph, as text or in the DOT language.
+func runCrawl(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	depth := fs.Int("depth", 2, "number of links to follow from the start page")
+	maxPages := fs.Int("max-pages", 500, "stop following links after this many pages, 0 for no limit")
+	workers := fs.Int("workers", 4, "number of pages fetched at the same time")
+	delay := fs.Duration("delay", 500*time.Millisecond, "minimum time between two requests to the website")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of every request")
+	dot := fs.Bool("dot", false, "write the graph in the DOT language of Graphviz")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("crawl: expected a single start URL")
+	}
+
+	c := crawl.Crawler{
+		Fetcher:  &fetch.Fetcher{Timeout: *timeout},
+		MaxDepth: *depth,
+		MaxPages: *maxPages,
+		Workers:  *workers,
+		Delay:    *delay,
+	}
+	// Ctrl+C stops the crawl, the pages fetched so far are still written.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	g, err := c.Crawl(ctx, fs.Arg(0))
+	if g == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "crawl: interrupted, the graph is incomplete")
+	}
+	if *dot {
+		return g.WriteDOT(w)
+	}
+	return g.WriteText(w)
+}
@@ -0,0 +1,195 @@
+// Package crawl follows the links of a website from a start page, fetching the pages concurrently, and builds the graph of the site.
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// Crawler crawls websites, its zero value fetches the start page only.
+type Crawler struct {
+	// Fetcher fetches the pages, its Workers and Ordered fields are not used.
+	Fetcher *fetch.Fetcher
+	// MaxDepth is the number of links followed from the start page, 0 only fetches the start page itself.
+	MaxDepth int
+	// MaxPages stops following links once that many pages have been found, no limit if 0.
+	MaxPages int
+	// Workers bounds the number of pages fetched at the same time, 1 if less than 1.
+	Workers int
+	// Delay is the politeness delay: the minimum time between the start of two requests to the same host, so that crawling doesn't overload it.
+	Delay time.Duration
+}
+
+// Crawl fetches start and follows the links to the same host, breadth first up to c.MaxDepth.
+// Links to other hosts are part of the graph but are not fetched.
+// Once ctx is done no more pages are fetched, the returned Graph holds the pages fetched so far along with ctx's error.
+func (c *Crawler) Crawl(ctx context.Context, start string) (*Graph, error) {
+	u, err := url.Parse(start)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("crawl: invalid start URL %q", start)
+	}
+	start = normalize(u)
+	fetcher := c.Fetcher
+	if fetcher == nil {
+		fetcher = &fetch.Fetcher{}
+	}
+
+	cr := &crawl{
+		Crawler: c,
+		fetcher: fetcher,
+		host:    u.Host,
+		visited: newVisited(c.MaxPages),
+		polite:  newPoliteness(c.Delay),
+		sem:     make(chan struct{}, max(c.Workers, 1)),
+		graph:   &Graph{Start: start},
+	}
+	cr.visited.Add(start)
+	cr.wg.Add(1)
+	go cr.visit(ctx, start, 0)
+	// Every visit adds the links it follows to the wait group before returning, so it only reaches zero once the whole site is done.
+	cr.wg.Wait()
+
+	cr.graph.sort()
+	return cr.graph, ctx.Err()
+}
+
+// crawl is the state of a single call to Crawl, shared by its goroutines.
+type crawl struct {
+	*Crawler
+	fetcher *fetch.Fetcher
+	host    string
+	visited *Visited
+	polite  *politeness
+	// sem bounds the goroutines fetching at the same time, the others wait for a slot.
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu    sync.Mutex // guards graph
+	graph *Graph
+}
+
+// visit fetches a page and starts a goroutine for every new link to follow.
+func (cr *crawl) visit(ctx context.Context, link string, depth int) {
+	defer cr.wg.Done()
+	select {
+	case cr.sem <- struct{}{}:
+		defer func() { <-cr.sem }()
+	case <-ctx.Done():
+		return
+	}
+	if !cr.polite.wait(ctx, cr.host) {
+		return
+	}
+
+	res := cr.fetcher.Fetch(ctx, link)
+	page := &Page{URL: link, Depth: depth, Status: res.Status, StatusCode: res.StatusCode, Err: res.Err, Kind: res.Kind, Elapsed: res.Elapsed}
+	if res.OK() && isHTML(res.Header.Get("Content-Type")) {
+		// The links are relative to the URL of the page after its redirects.
+		base, err := url.Parse(res.URL)
+		if err != nil {
+			base, _ = url.Parse(link) // it was parsed before, when it was found
+		}
+		page.Links = Links(base, res.Body)
+	}
+	cr.mu.Lock()
+	cr.graph.Pages = append(cr.graph.Pages, page)
+	cr.mu.Unlock()
+
+	if depth >= cr.MaxDepth {
+		return
+	}
+	for _, l := range page.Links {
+		u, _ := url.Parse(l)
+		if u.Host != cr.host || !cr.visited.Add(l) {
+			continue
+		}
+		cr.wg.Add(1)
+		go cr.visit(ctx, l, depth+1)
+	}
+}
+
+// isHTML reports whether a Content-Type is HTML, a missing one is sniffed as HTML by most servers serving pages.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// Visited is a set of URLs safe for concurrent use, so that every page is fetched once however many pages link to it.
+type Visited struct {
+	mu    sync.Mutex
+	seen  map[string]bool
+	limit int
+}
+
+// newVisited returns an empty set holding at most limit URLs, no limit if 0.
+func newVisited(limit int) *Visited {
+	return &Visited{seen: map[string]bool{}, limit: limit}
+}
+
+// Add adds link and reports whether it was added, false if it was already there or the set is full.
+// Checking and adding under the same lock matters: with a separate Contains, two goroutines could both see a link as new.
+func (v *Visited) Add(link string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.seen[link] || (v.limit > 0 && len(v.seen) >= v.limit) {
+		return false
+	}
+	v.seen[link] = true
+	return true
+}
+
+// Len returns the number of URLs in the set.
+func (v *Visited) Len() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.seen)
+}
+
+// politeness spaces the requests to every host by at least delay.
+type politeness struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time // earliest start of the next request to the host
+}
+
+func newPoliteness(delay time.Duration) *politeness {
+	return &politeness{delay: delay, next: map[string]time.Time{}}
+}
+
+// wait blocks until a request to host may start, and reports false if ctx was done first.
+// Every caller reserves its own slot under the lock and then sleeps without it, so that concurrent callers queue up delay apart.
+func (p *politeness) wait(ctx context.Context, host string) bool {
+	// Checked first, as the select below picks at random between a timer already due and a done ctx.
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.delay <= 0 {
+		return true
+	}
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[host] = at.Add(p.delay)
+	p.mu.Unlock()
+
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,306 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+// testSite is a website whose pages link back to each other, with pages which aren't HTML and a broken link.
+var testSite = map[string]struct{ contentType, body string }{
+	"/": {"text/html; charset=utf-8", `<a href="/a">A</a> <a href="b#top">B</a> <a href="/notes.txt">Notes</a> <a href="/logo.png">Logo</a>
+		<a href="/missing">Broken</a> <a href="https://other.example/">Elsewhere</a>`},
+	"/a":        {"text/html", `<a href="/">Home</a> <a href="/b">B</a> <a href="/a/deep">Deep</a>`},
+	"/b":        {"text/html", `<a href="/a">A</a> <a href="/b">Itself</a>`},
+	"/a/deep":   {"application/xhtml+xml", `<a href="/a/deeper">Deeper</a>`},
+	"/a/deeper": {"text/html", `<a href="/">Home</a>`},
+	// Not HTML, so the link in it isn't followed.
+	"/notes.txt": {"text/plain", `<a href="/hidden">Hidden</a>`},
+	"/logo.png":  {"image/png", `<a href="/hidden">`},
+	"/hidden":    {"text/html", ``},
+}
+
+// siteServer serves testSite, counting the requests to every path and recording when they start.
+type siteServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string]int
+	starts   []time.Time
+}
+
+func newSiteServer() *siteServer {
+	s := &siteServer{requests: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.starts = append(s.starts, time.Now())
+		s.mu.Unlock()
+		page, ok := testSite[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page.contentType)
+		fmt.Fprint(w, page.body)
+	}))
+	return s
+}
+
+// fetched returns the number of requests per path.
+func (s *siteServer) fetched() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// urls returns the URLs of the pages of g along with their depth, e.g. "1 /a".
+func urls(g *Graph, site string) []string {
+	var got []string
+	for _, p := range g.Pages {
+		got = append(got, fmt.Sprintf("%d %s", p.Depth, strings.TrimPrefix(p.URL, site)))
+	}
+	return got
+}
+
+func TestCrawl(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := newSiteServer()
+	defer srv.Close()
+
+	c := Crawler{Fetcher: &fetch.Fetcher{Client: srv.Client()}, MaxDepth: 2, Workers: 4}
+	g, err := c.Crawl(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// /a/deeper is 3 links away from the start, so it is found but not fetched.
+	want := []string{"0 /", "1 /a", "1 /b", "1 /logo.png", "1 /missing", "1 /notes.txt", "2 /a/deep"}
+	if got := urls(g, srv.URL); !slices.Equal(got, want) {
+		t.Errorf("pages = %q, want %q", got, want)
+	}
+	if g.Start != srv.URL+"/" {
+		t.Errorf("Start = %q, want %q", g.Start, srv.URL+"/")
+	}
+	for path, n := range srv.fetched() {
+		if n != 1 {
+			t.Errorf("%s fetched %d times, want once however many pages link to it", path, n)
+		}
+	}
+	if n := srv.fetched()["/hidden"]; n != 0 {
+		t.Error("followed a link found in a page which isn't HTML")
+	}
+
+	home := g.Page(srv.URL + "/")
+	wantLinks := []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/logo.png", srv.URL + "/missing", srv.URL + "/notes.txt", "https://other.example/"}
+	if !slices.Equal(home.Links, wantLinks) {
+		t.Errorf("links of / = %q, want %q", home.Links, wantLinks)
+	}
+	if p := g.Page(srv.URL + "/notes.txt"); p.Links != nil || p.StatusCode != 200 {
+		t.Errorf("/notes.txt = %d with links %q, want 200 without links", p.StatusCode, p.Links)
+	}
+	if p := g.Page(srv.URL + "/missing"); p.StatusCode != 404 || p.Err == nil {
+		t.Errorf("/missing = %d (%v), want a 404", p.StatusCode, p.Err)
+	}
+	if p := g.Page(srv.URL + "/a/deeper"); p != nil {
+		t.Errorf("/a/deeper fetched at depth %d past MaxDepth", p.Depth)
+	}
+	if p := g.Page("https://other.example/"); p != nil {
+		t.Error("fetched a page of another host")
+	}
+}
+
+func TestCrawlLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		depth     int
+		pages     int
+		wantPages int
+	}{
+		{"start only", 0, 0, 1},
+		{"one link away", 1, 0, 6},
+		{"whole site", 10, 0, 8},
+		{"max pages", 10, 3, 3},
+		{"max pages past the site", 10, 100, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSiteServer()
+			defer srv.Close()
+			c := Crawler{Fetcher: &fetch.Fetcher{Client: srv.Client()}, MaxDepth: tt.depth, MaxPages: tt.pages, Workers: 2}
+			g, err := c.Crawl(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(g.Pages) != tt.wantPages || len(srv.fetched()) != tt.wantPages {
+				t.Errorf("%d pages, %d fetched, want %d: %q", len(g.Pages), len(srv.fetched()), tt.wantPages, urls(g, srv.URL))
+			}
+		})
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := newSiteServer()
+	defer srv.Close()
+
+	const delay = 20 * time.Millisecond
+	c := Crawler{Fetcher: &fetch.Fetcher{Client: srv.Client()}, MaxDepth: 1, Workers: 4, Delay: delay}
+	start := time.Now()
+	if _, err := c.Crawl(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	// The n requests go to the same host, so even 4 workers start them one at a time and delay apart.
+	// A timer firing late can bring two requests closer, but the last one can't start before n-1 delays.
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	n := len(srv.starts)
+	if last := srv.starts[n-1].Sub(start); last < time.Duration(n-1)*delay {
+		t.Errorf("the last of %d requests started after %s, want at least %s", n, last, time.Duration(n-1)*delay)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := newSiteServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := Crawler{Fetcher: &fetch.Fetcher{Client: srv.Client()}, MaxDepth: 2, Delay: time.Hour}
+	g, err := c.Crawl(ctx, srv.URL)
+	if !errors.Is(err, context.Canceled) || len(g.Pages) != 0 {
+		t.Errorf("Crawl with a cancelled ctx = %d pages, %v, want none and context.Canceled", len(g.Pages), err)
+	}
+}
+
+func TestCrawlInvalidStart(t *testing.T) {
+	for _, start := range []string{"", "example.com", "mailto:me@example.com", "http://", "http://[::1"} {
+		if _, err := (&Crawler{}).Crawl(context.Background(), start); err == nil {
+			t.Errorf("Crawl(%q) succeeded", start)
+		}
+	}
+}
+
+func TestIsHTML(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"", true},
+		{"text/html", true},
+		{"text/html; charset=utf-8", true},
+		{"TEXT/HTML", true},
+		{"application/xhtml+xml", true},
+		{"text/plain", false},
+		{"image/png", false},
+		{"application/json", false},
+		{";;", false},
+	}
+	for _, tt := range tests {
+		if got := isHTML(tt.contentType); got != tt.want {
+			t.Errorf("isHTML(%q) = %t, want %t", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestVisited(t *testing.T) {
+	const goroutines, links = 8, 100
+	v := newVisited(0)
+	var added [links]int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range links {
+				if v.Add(fmt.Sprint("/page/", i)) {
+					mu.Lock()
+					added[i]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// Every link is added by exactly one of the goroutines, the others see it as visited.
+	for i, n := range added {
+		if n != 1 {
+			t.Errorf("/page/%d added %d times", i, n)
+		}
+	}
+	if v.Len() != links {
+		t.Errorf("Len() = %d, want %d", v.Len(), links)
+	}
+
+	limited := newVisited(2)
+	for i, want := range []bool{true, true, false} {
+		if got := limited.Add(fmt.Sprint("/", i)); got != want {
+			t.Errorf("Add of link %d to a set of at most 2 = %t, want %t", i+1, got, want)
+		}
+	}
+	if limited.Add("/0") || limited.Len() != 2 {
+		t.Errorf("a full set took a link, Len() = %d", limited.Len())
+	}
+}
+
+func TestPoliteness(t *testing.T) {
+	defer leak.Verify(t)()
+	const delay, n = 20 * time.Millisecond, 5
+	p := newPoliteness(delay)
+	start := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var done []time.Duration
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !p.wait(context.Background(), "a.example") {
+				t.Error("wait failed without a cancelled ctx")
+			}
+			mu.Lock()
+			done = append(done, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	// Every caller reserved its own slot delay after the previous one, so the last one waited for n-1 delays.
+	slices.Sort(done)
+	if done[n-1] < (n-1)*delay {
+		t.Errorf("%d waits for the same host done after %s, want at least %s", n, done[n-1], (n-1)*delay)
+	}
+
+	// Another host has its own slots: it doesn't queue behind a.example.
+	slow := newPoliteness(time.Hour)
+	slow.wait(context.Background(), "a.example")
+	begin := time.Now()
+	if !slow.wait(context.Background(), "b.example") || time.Since(begin) > time.Second {
+		t.Error("the first request to b.example waited for a.example")
+	}
+
+	// A cancelled ctx stops the wait for the next slot of a.example, an hour away.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	begin = time.Now()
+	if slow.wait(ctx, "a.example") {
+		t.Error("wait returned true after ctx was cancelled")
+	}
+	if time.Since(begin) > time.Second {
+		t.Errorf("wait returned %s after ctx was cancelled", time.Since(begin))
+	}
+	// So does a ctx done before waiting, even without a delay.
+	if newPoliteness(0).wait(ctx, "a.example") {
+		t.Error("wait without a delay returned true for a cancelled ctx")
+	}
+}
@@ -0,0 +1,107 @@
+package crawl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// Page is a fetched page of the site, and the links found on it.
+type Page struct {
+	URL        string
+	Depth      int
+	Status     string
+	StatusCode int
+	Err        error
+	Kind       fetch.ErrorKind
+	Elapsed    time.Duration
+	// Links are the absolute URLs the page links to, sorted, including the links to other hosts.
+	Links []string
+}
+
+// Graph is the site found by Crawl: its pages are the nodes and their links the edges.
+type Graph struct {
+	Start string
+	// Pages are sorted by depth, then by URL.
+	Pages []*Page
+	index map[string]*Page
+}
+
+// sort orders the pages, which are found in a different order on every run as they are fetched concurrently.
+func (g *Graph) sort() {
+	sort.Slice(g.Pages, func(i, j int) bool {
+		if g.Pages[i].Depth != g.Pages[j].Depth {
+			return g.Pages[i].Depth < g.Pages[j].Depth
+		}
+		return g.Pages[i].URL < g.Pages[j].URL
+	})
+	g.index = make(map[string]*Page, len(g.Pages))
+	for _, p := range g.Pages {
+		g.index[p.URL] = p
+	}
+}
+
+// Page returns the fetched page with the URL, nil if it wasn't fetched.
+func (g *Graph) Page(url string) *Page {
+	return g.index[url]
+}
+
+// WriteText writes every page with its status and its links, indented.
+// Links to pages which weren't fetched (other hosts, or beyond the depth) are marked with "(not crawled)".
+func (g *Graph) WriteText(w io.Writer) error {
+	for _, p := range g.Pages {
+		status := p.Status
+		if p.Err != nil && p.StatusCode == 0 {
+			status = fmt.Sprintf("error (%s): %v", p.Kind, p.Err)
+		}
+		if _, err := fmt.Fprintf(w, "%s [depth %d] %s\n", p.URL, p.Depth, status); err != nil {
+			return err
+		}
+		for _, l := range p.Links {
+			note := ""
+			if g.Page(l) == nil {
+				note = " (not crawled)"
+			}
+			if _, err := fmt.Fprintf(w, "    -> %s%s\n", l, note); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz, e.g. `dot -Tsvg site.dot -o site.svg` draws it.
+// Failed pages are drawn in red and pages which weren't fetched dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph site {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	external := map[string]bool{}
+	for _, p := range g.Pages {
+		attrs := ""
+		if p.Err != nil {
+			attrs = " [color=red]"
+		}
+		fmt.Fprintf(&sb, "\t%q%s;\n", p.URL, attrs)
+		for _, l := range p.Links {
+			if g.Page(l) == nil {
+				external[l] = true
+			}
+			fmt.Fprintf(&sb, "\t%q -> %q;\n", p.URL, l)
+		}
+	}
+	others := make([]string, 0, len(external))
+	for l := range external {
+		others = append(others, l)
+	}
+	sort.Strings(others)
+	for _, l := range others {
+		fmt.Fprintf(&sb, "\t%q [style=dashed];\n", l)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,161 @@
+package crawl
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Links returns the absolute URLs of the `<a href>` links of an HTML page, resolved against base (or the page's `<base href>`), without duplicates and sorted.
+// It is a small scanner rather than a full HTML parser: it skips comments, scripts and styles, which is enough for the links of ordinary pages.
+func Links(base *url.URL, body []byte) []string {
+	seen := map[string]bool{}
+	for i := 0; i < len(body); {
+		lt := bytes.IndexByte(body[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt + 1
+		rest := body[i:]
+		if bytes.HasPrefix(rest, []byte("!--")) {
+			i += skipPast(rest, "-->")
+			continue
+		}
+
+		name, attrs, n := parseTag(rest)
+		i += n
+		switch name {
+		case "script", "style":
+			// Their content is not HTML, a "<a" inside a script string is not a link.
+			i += skipPast(body[i:], "</"+name)
+		case "base":
+			if href, ok := attrs["href"]; ok {
+				if u, err := base.Parse(href); err == nil {
+					base = u
+				}
+			}
+		case "a":
+			if href, ok := attrs["href"]; ok {
+				if link, ok := resolve(base, href); ok {
+					seen[link] = true
+				}
+			}
+		}
+	}
+
+	links := make([]string, 0, len(seen))
+	for link := range seen {
+		links = append(links, link)
+	}
+	slices.Sort(links)
+	return links
+}
+
+// skipPast returns the number of bytes up to and including the end of the first (case insensitive) occurrence of s, or the length of b.
+func skipPast(b []byte, s string) int {
+	i := bytes.Index(bytes.ToLower(b), []byte(s))
+	if i < 0 {
+		return len(b)
+	}
+	return i + len(s)
+}
+
+// parseTag parses the name and attributes of the tag starting at b (just after its "<"), and returns the number of bytes up to and including its ">".
+// The names are lower cased and the values unescaped, end tags and declarations have no name, and n is 0 if b doesn't start a tag at all.
+func parseTag(b []byte) (name string, attrs map[string]string, n int) {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+	isNameEnd := func(c byte) bool { return isSpace(c) || c == '>' || c == '/' || c == '=' }
+
+	i := 0
+	for i < len(b) && !isNameEnd(b[i]) {
+		i++
+	}
+	if i == 0 || !isLetter(b[0]) {
+		if len(b) > 0 && (b[0] == '/' || b[0] == '!' || b[0] == '?') {
+			// "</a>", "<!DOCTYPE html>" or "<?xml ...?>"
+			return "", nil, skipPast(b, ">")
+		}
+		// A "<" not followed by a tag name is text, like in "a < b": skipping to the next ">" would swallow the tag after it.
+		return "", nil, 0
+	}
+	name = strings.ToLower(string(b[:i]))
+
+	attrs = map[string]string{}
+	for i < len(b) {
+		for i < len(b) && (isSpace(b[i]) || b[i] == '/') {
+			i++
+		}
+		if i >= len(b) || b[i] == '>' {
+			break
+		}
+		start := i
+		for i < len(b) && !isNameEnd(b[i]) {
+			i++
+		}
+		key := strings.ToLower(string(b[start:i]))
+		if i == start {
+			i++ // a stray "=", the scan must go on
+			continue
+		}
+		for i < len(b) && isSpace(b[i]) {
+			i++
+		}
+		if i >= len(b) || b[i] != '=' {
+			attrs[key] = "" // attribute without a value, like `disabled`
+			continue
+		}
+		i++
+		for i < len(b) && isSpace(b[i]) {
+			i++
+		}
+		var value []byte
+		if i < len(b) && (b[i] == '"' || b[i] == '\'') {
+			quote := b[i]
+			end := bytes.IndexByte(b[i+1:], quote)
+			if end < 0 {
+				value, i = b[i+1:], len(b)
+			} else {
+				value, i = b[i+1:i+1+end], i+end+2
+			}
+		} else {
+			start := i
+			for i < len(b) && !isSpace(b[i]) && b[i] != '>' {
+				i++
+			}
+			value = b[start:i]
+		}
+		if _, dup := attrs[key]; !dup { // the first one wins, like in browsers
+			attrs[key] = html.UnescapeString(string(value))
+		}
+	}
+	if i < len(b) {
+		i++ // the ">"
+	}
+	return name, attrs, i
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// resolve makes href absolute, dropping its fragment as it points into the same page.
+// Links that can't be fetched (mailto:, javascript:, etc.) are rejected.
+func resolve(base *url.URL, href string) (string, bool) {
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return normalize(u), true
+}
+
+// normalize gives the same string for URLs that only differ in ways that don't change the page, so that the visited set sees them as one.
+func normalize(u *url.URL) string {
+	u.Fragment, u.RawFragment = "", ""
+	u.Scheme, u.Host = strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
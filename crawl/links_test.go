@@ -0,0 +1,143 @@
+package crawl
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/dir/page.html")
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"double quoted", `<a href="x">x</a>`, []string{"http://example.com/dir/x"}},
+		{"single quoted", `<a href='/y'>y</a>`, []string{"http://example.com/y"}},
+		{"unquoted", `<a href=z>z</a> <a href=/w>w</a>`, []string{"http://example.com/dir/z", "http://example.com/w"}},
+		{"spaces around =", "<a href = \"/sp\">", []string{"http://example.com/sp"}},
+		{"newline before the attribute", "<a\nhref=\"/nl\">", []string{"http://example.com/nl"}},
+		{"other attributes first", `<a class="nav" data-x=1 disabled href="/last">`, []string{"http://example.com/last"}},
+		{"first href wins", `<a href="/first" href="/second">`, []string{"http://example.com/first"}},
+		{"uppercase", `<A HREF="/upper">Up</A>`, []string{"http://example.com/upper"}},
+		{"escaped", `<a href="/q?a=1&amp;b=2">`, []string{"http://example.com/q?a=1&b=2"}},
+		{"comment", `<!-- <a href="/hidden"> --> <a href="/shown">`, []string{"http://example.com/shown"}},
+		{"unclosed comment", `<!-- <a href="/hidden">`, []string{}},
+		{"script", `<script>document.write('<a href="/s">')</script><a href="/after">`, []string{"http://example.com/after"}},
+		{"uppercase script", `<SCRIPT>x = "<a href='/s'>"</SCRIPT><a href="/after">`, []string{"http://example.com/after"}},
+		{"style", `<style>a[href="<a href='/s'>"] {}</style>`, []string{}},
+		{"fragment", `<a href="/f#top">`, []string{"http://example.com/f"}},
+		{"fragment only", `<a href="#top">`, []string{"http://example.com/dir/page.html"}},
+		{"relative up", `<a href="../up">`, []string{"http://example.com/up"}},
+		{"query only", `<a href="?page=2">`, []string{"http://example.com/dir/page.html?page=2"}},
+		{"protocol-relative", `<a href="//Other.Example/p">`, []string{"http://other.example/p"}},
+		{"absolute", `<a href="HTTPS://Example.COM">`, []string{"https://example.com/"}},
+		{"mailto and javascript", `<a href="mailto:me@example.com"> <a href="javascript:void(0)"> <a href="ftp://example.com/f">`, []string{}},
+		{"no href", `<a name="top">`, []string{}},
+		{"not an a tag", `<abbr href="/abbr"> <link href="/style.css"> <img src="/img.png">`, []string{}},
+		{"base", `<a href="before"> <base href="/other/"> <a href="after">`, []string{"http://example.com/dir/before", "http://example.com/other/after"}},
+		{"duplicates sorted", `<a href="/b"> <a href="/a"> <a href="/b#x"> <a href="/a">`, []string{"http://example.com/a", "http://example.com/b"}},
+		{"end tags and doctype", `<!DOCTYPE html><html><body></body></html><a href="/x">`, []string{"http://example.com/x"}},
+		{"stray less than", `if a < b <a href="/x">x</a>`, []string{"http://example.com/x"}},
+		{"unterminated tag", `<a href="/x"`, []string{"http://example.com/x"}},
+		{"unterminated quote", `<a href="/x>`, []string{"http://example.com/x%3E"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Links changes the base it is given after a <base href>, every case starts from the same one.
+			b := *base
+			if got := Links(&b, []byte(tt.body)); !slices.Equal(got, tt.want) {
+				t.Errorf("Links(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag   string // what follows the "<"
+		name  string
+		attrs map[string]string
+		n     int
+	}{
+		{`a href="/x">rest`, "a", map[string]string{"href": "/x"}, 12},
+		{`IMG SRC=a.png ALT='A b'/>`, "img", map[string]string{"src": "a.png", "alt": "A b"}, 25},
+		{`input disabled value = "v">`, "input", map[string]string{"disabled": "", "value": "v"}, 27},
+		{`a =x href=/y>`, "a", map[string]string{"x": "", "href": "/y"}, 13},
+		{`br/>`, "br", map[string]string{}, 4},
+		{`a href="/x"`, "a", map[string]string{"href": "/x"}, 11},
+		{`/a>text`, "", nil, 3},
+		{`!DOCTYPE html>`, "", nil, 14},
+		{` b >`, "", nil, 0}, // "a < b" is text
+		{`3 <a>`, "", nil, 0},
+	}
+	for _, tt := range tests {
+		name, attrs, n := parseTag([]byte(tt.tag))
+		if name != tt.name || !maps.Equal(attrs, tt.attrs) || n != tt.n {
+			t.Errorf("parseTag(%q) = %q, %q, %d, want %q, %q, %d", tt.tag, name, attrs, n, tt.name, tt.attrs, tt.n)
+		}
+	}
+}
+
+func TestSkipPast(t *testing.T) {
+	tests := []struct {
+		b, s string
+		want int
+	}{
+		{"x --> y", "-->", 5},
+		{"var a;</SCRIPT>", "</script", 14},
+		{"no end", "-->", 6},
+		{"", ">", 0},
+	}
+	for _, tt := range tests {
+		if got := skipPast([]byte(tt.b), tt.s); got != tt.want {
+			t.Errorf("skipPast(%q, %q) = %d, want %d", tt.b, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"http://example.com", "http://example.com/"},
+		{"HTTP://EXAMPLE.com/Path", "http://example.com/Path"},
+		{"http://example.com/a#frag", "http://example.com/a"},
+		{"http://example.com/a?q=1#frag", "http://example.com/a?q=1"},
+		{"https://example.com:8443/", "https://example.com:8443/"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := normalize(u); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/b")
+	tests := []struct {
+		href string
+		want string
+		ok   bool
+	}{
+		{"c", "https://example.com/a/c", true},
+		{"  /c  ", "https://example.com/c", true},
+		{"//cdn.example.com/x", "https://cdn.example.com/x", true},
+		{"http://other.example", "http://other.example/", true},
+		{"mailto:me@example.com", "", false},
+		{"javascript:alert(1)", "", false},
+		{"data:text/plain,hi", "", false},
+		{"http://[::1", "", false},
+		{"http:///no-host", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := resolve(base, tt.href)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("resolve(%q) = %q, %t, want %q, %t", tt.href, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// body returns n bytes of text, different at every position so that a body cut at the wrong place doesn't hash the same.
+func body(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 'a' + byte(i%26)
+	}
+	return b
+}
+
+// bodyServer answers /<n> with body(n), with a Content-Length unless the query is ?chunked.
+func bodyServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.RawQuery == "chunked" {
+			// Flushing before writing the body makes it chunked, without a Content-Length.
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(n))
+		}
+		w.Write(body(n))
+	}))
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func TestMaxBodySize(t *testing.T) {
+	srv := bodyServer()
+	defer srv.Close()
+
+	const limit = 100
+	tests := []struct {
+		name          string
+		maxBodySize   int64
+		size          int
+		wantSize      int
+		wantTruncated bool
+	}{
+		{"empty", limit, 0, 0, false},
+		{"below the limit", limit, limit - 1, limit - 1, false},
+		// Exactly the limit reads the extra byte to find out, and finds none.
+		{"exactly the limit", limit, limit, limit, false},
+		{"one past the limit", limit, limit + 1, limit, true},
+		{"far past the limit", limit, 10 * limit, limit, true},
+		{"no limit", -1, 10 * limit, 10 * limit, false},
+		{"default limit", 0, DefaultMaxBodySize, DefaultMaxBodySize, false},
+		{"past the default limit", 0, DefaultMaxBodySize + 1, DefaultMaxBodySize, true},
+	}
+	for _, tt := range tests {
+		for _, query := range []string{"", "?chunked"} {
+			t.Run(tt.name+query, func(t *testing.T) {
+				for _, discard := range []bool{false, true} {
+					f := Fetcher{Client: srv.Client(), MaxBodySize: tt.maxBodySize, DiscardBody: discard}
+					res := f.Fetch(context.Background(), srv.URL+"/"+strconv.Itoa(tt.size)+query)
+					if !res.OK() {
+						t.Fatalf("Fetch = %v", res.Err)
+					}
+					want := body(tt.size)[:tt.wantSize]
+					if res.BodySize != int64(tt.wantSize) || res.Truncated != tt.wantTruncated {
+						t.Errorf("DiscardBody %t: BodySize %d, Truncated %t, want %d, %t", discard, res.BodySize, res.Truncated, tt.wantSize, tt.wantTruncated)
+					}
+					// The hash is computed on the fly, of what was read: the start of the body if it was truncated.
+					if res.ContentHash != sha256Hex(want) {
+						t.Errorf("DiscardBody %t: ContentHash %s, want the SHA-256 of the %d bytes read %s", discard, res.ContentHash, tt.wantSize, sha256Hex(want))
+					}
+					switch {
+					case discard && res.Body != nil:
+						t.Errorf("DiscardBody kept a body of %d bytes", len(res.Body))
+					case !discard && !bytes.Equal(res.Body, want):
+						t.Errorf("Body is %d bytes, want the first %d bytes served", len(res.Body), tt.wantSize)
+					}
+				}
+			})
+		}
+	}
+}
+
+// trackedBody is a response body recording whether it was closed, failing with err once its data has been read if err is set.
+type trackedBody struct {
+	io.Reader
+	err    error
+	closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF && b.err != nil {
+		err = b.err
+	}
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+// roundTripFunc makes a function an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestBodyClosed checks that the body is closed on every way out of a request: read fully, truncated, a failed status and a failed read.
+func TestBodyClosed(t *testing.T) {
+	errReset := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		readErr error
+		wantOK  bool
+	}{
+		{"read fully", 200, "ok", nil, true},
+		{"truncated", 200, strings.Repeat("x", 20), nil, true},
+		{"failed status", 500, "oops", nil, false},
+		{"failed read", 200, "partial", errReset, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &trackedBody{Reader: strings.NewReader(tt.body), err: tt.readErr}
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: tt.status, Status: http.StatusText(tt.status), Header: http.Header{}, Body: b, Request: r}, nil
+			})
+			f := Fetcher{Client: &http.Client{Transport: transport}, MaxBodySize: 10}
+			res := f.Fetch(context.Background(), "http://example.com/")
+			if res.OK() != tt.wantOK {
+				t.Errorf("Fetch = %v, want OK: %t", res.Err, tt.wantOK)
+			}
+			if tt.readErr != nil && (!errors.Is(res.Err, tt.readErr) || res.ContentHash != "") {
+				t.Errorf("failed read = %v with hash %q, want %v and no hash", res.Err, res.ContentHash, tt.readErr)
+			}
+			if !b.closed {
+				t.Error("the body wasn't closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package fetch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+)
+
+// ErrCircuitOpen is the error of a Result whose request wasn't made because the circuit of its host is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of the circuit of a host.
+type CircuitState string
+
+const (
+	// CircuitClosed lets the requests through, counting the consecutive failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails the requests straight away, until the cool-down is over.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a few probe requests through, to find out whether the host is back.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// Breaker is a circuit breaker per host: once a host has failed Threshold times in a row, its requests fail with ErrCircuitOpen without being made.
+// When a host is down every request to it would otherwise wait for the full timeout, slowing down every check and piling up on the host when it comes back.
+//
+// After Cooldown the circuit is half-open: up to Probes requests are let through.
+// If they all succeed the circuit is closed again, if one fails it is open for another Cooldown.
+// The zero value is ready to use, with the defaults below. Its fields must be set before its first use.
+type Breaker struct {
+	// Threshold is the number of consecutive failures opening the circuit, 5 if 0.
+	Threshold int
+	// Cooldown is how long the circuit stays open before probing the host, 30s if 0.
+	Cooldown time.Duration
+	// Probes is the number of successful probes closing the circuit again, and the most probes in flight at once, 1 if 0.
+	Probes int
+	// IsFailure decides which results count as failures, failed requests without a response and 5xx statuses if nil.
+	// A 4xx status is a problem with the request rather than with the host, so it doesn't count by default.
+	IsFailure func(Result) bool
+	// OnStateChange is called on every change of state of a circuit, while the breaker's lock is held, so it must not use the breaker.
+	OnStateChange func(host string, from, to CircuitState)
+	// Clock times the cool-down, clock.Real if nil.
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the circuit was last opened
+	probing  int       // probes in flight while half-open
+	passed   int       // successful probes while half-open
+}
+
+func (b *Breaker) now() time.Time {
+	if b.Clock == nil {
+		return time.Now()
+	}
+	return b.Clock.Now()
+}
+
+// circuit returns the circuit of host, the caller holds b.mu.
+func (b *Breaker) circuit(host string) *circuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		if b.hosts == nil {
+			b.hosts = map[string]*circuit{}
+		}
+		c = &circuit{state: CircuitClosed}
+		b.hosts[host] = c
+	}
+	return c
+}
+
+// set changes the state of the circuit of host, the caller holds b.mu.
+func (b *Breaker) set(host string, c *circuit, to CircuitState) {
+	from := c.state
+	c.state, c.failures, c.probing, c.passed = to, 0, 0, 0
+	if to == CircuitOpen {
+		c.openedAt = b.now()
+	}
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(host, from, to)
+	}
+}
+
+// State returns the state of the circuit of host, moving it to half-open if its cool-down is over.
+func (b *Breaker) State(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	b.cool(host, c)
+	return c.state
+}
+
+// cool half-opens the circuit once its cool-down is over, the caller holds b.mu.
+func (b *Breaker) cool(host string, c *circuit) {
+	cooldown := b.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= cooldown {
+		b.set(host, c, CircuitHalfOpen)
+	}
+}
+
+// allow reports whether a request to host may be made, and the state of its circuit.
+// A request allowed while half-open is a probe, which must be followed by a call to done.
+func (b *Breaker) allow(host string) (CircuitState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	b.cool(host, c)
+	switch c.state {
+	case CircuitOpen:
+		return c.state, false
+	case CircuitHalfOpen:
+		if c.probing >= max(b.Probes, 1) {
+			return c.state, false
+		}
+		c.probing++
+	}
+	return c.state, true
+}
+
+// done records the result of a request allowed by allow(host), and returns the state of the circuit afterwards.
+func (b *Breaker) done(host string, res Result) CircuitState {
+	isFailure := b.IsFailure
+	if isFailure == nil {
+		isFailure = func(res Result) bool { return res.Err != nil && (res.StatusCode == 0 || res.StatusCode >= 500) }
+	}
+	failed := isFailure(res)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	// A request cancelled by its caller tells nothing about the host, it only frees its probe slot.
+	if res.Kind == KindCanceled {
+		if c.state == CircuitHalfOpen {
+			c.probing = max(c.probing-1, 0)
+		}
+		return c.state
+	}
+	switch c.state {
+	case CircuitClosed:
+		if !failed {
+			c.failures = 0
+			break
+		}
+		c.failures++
+		threshold := b.Threshold
+		if threshold <= 0 {
+			threshold = 5
+		}
+		if c.failures >= threshold {
+			b.set(host, c, CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		// max, as a probe of a previous half-open period can finish after the circuit was reopened and half-opened again.
+		c.probing = max(c.probing-1, 0)
+		if failed {
+			b.set(host, c, CircuitOpen)
+			break
+		}
+		c.passed++
+		if c.passed >= max(b.Probes, 1) {
+			b.set(host, c, CircuitClosed)
+		}
+	}
+	// When open, the request was let through before another one opened the circuit, its result changes nothing.
+	return c.state
+}
@@ -0,0 +1,111 @@
+package fetch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+)
+
+var (
+	ok       = Result{Status: "200 OK", StatusCode: 200}
+	notFound = Result{Status: "404 Not Found", StatusCode: 404, Err: errors.New("404"), Kind: KindHTTP}
+	broken   = Result{Status: "500 Internal Server Error", StatusCode: 500, Err: errors.New("500"), Kind: KindHTTP}
+	refused  = Result{Err: errors.New("connection refused"), Kind: KindConnection}
+	canceled = Result{Err: errors.New("context canceled"), Kind: KindCanceled}
+)
+
+// request makes a request through b with the given result, if it is allowed.
+func request(b *Breaker, res Result) (CircuitState, bool) {
+	state, ok := b.allow("host")
+	if !ok {
+		return state, false
+	}
+	return b.done("host", res), true
+}
+
+func TestBreakerThreshold(t *testing.T) {
+	b := &Breaker{Threshold: 3, Clock: clock.NewFake(time.Now())}
+	// A success starts the count again, and so does a 4xx: the host answered, the problem is the request.
+	for _, res := range []Result{refused, broken, ok, refused, notFound, refused, broken} {
+		if state, _ := request(b, res); state != CircuitClosed {
+			t.Fatalf("circuit %s before 3 failures in a row", state)
+		}
+	}
+	if state, _ := request(b, refused); state != CircuitOpen {
+		t.Fatalf("circuit %s after 3 failures in a row, want open", state)
+	}
+	if state, allowed := request(b, ok); allowed || state != CircuitOpen {
+		t.Errorf("a request was allowed while the circuit was open")
+	}
+	if b.State("other") != CircuitClosed {
+		t.Error("the circuit of another host was opened")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var changes []CircuitState
+	b := &Breaker{Threshold: 1, Cooldown: time.Minute, Probes: 2, Clock: fake,
+		OnStateChange: func(host string, from, to CircuitState) { changes = append(changes, to) }}
+	request(b, refused)
+
+	fake.Advance(time.Minute - 1)
+	if b.State("host") != CircuitOpen {
+		t.Fatal("the circuit half-opened before the cool-down was over")
+	}
+	fake.Advance(1)
+	if b.State("host") != CircuitHalfOpen {
+		t.Fatal("the circuit didn't half-open after the cool-down")
+	}
+
+	// At most Probes requests are in flight at once.
+	_, first := b.allow("host")
+	_, second := b.allow("host")
+	if _, third := b.allow("host"); !first || !second || third {
+		t.Fatalf("probes allowed: %v %v %v, want the first 2 only", first, second, third)
+	}
+	// A cancelled probe frees its slot without counting.
+	if state := b.done("host", canceled); state != CircuitHalfOpen {
+		t.Fatalf("circuit %s after a cancelled probe, want half_open", state)
+	}
+	if b.done("host", ok) != CircuitHalfOpen {
+		t.Fatal("the circuit closed after 1 of 2 probes")
+	}
+	if state, _ := request(b, ok); state != CircuitClosed {
+		t.Fatalf("circuit %s after 2 successful probes, want closed", state)
+	}
+
+	// A failed probe opens the circuit again, for another cool-down.
+	request(b, refused)
+	fake.Advance(time.Minute)
+	if state, _ := request(b, broken); state != CircuitOpen {
+		t.Fatalf("circuit %s after a failed probe, want open", state)
+	}
+	if b.State("host") != CircuitOpen {
+		t.Error("the cool-down didn't start again")
+	}
+
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed, CircuitOpen, CircuitHalfOpen, CircuitOpen}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("state changes = %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestBreakerIsFailure(t *testing.T) {
+	b := &Breaker{Threshold: 1, IsFailure: func(res Result) bool { return res.StatusCode == 404 }}
+	request(b, broken)
+	if b.State("host") != CircuitClosed {
+		t.Error("a 5xx opened the circuit although IsFailure said it isn't a failure")
+	}
+	request(b, notFound)
+	if b.State("host") != CircuitOpen {
+		t.Error("a 404 didn't open the circuit although IsFailure said it is a failure")
+	}
+}
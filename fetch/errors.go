@@ -0,0 +1,79 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+)
+
+// ErrorKind tells which part of fetching a website failed, so that failures can be counted and reported without parsing error messages.
+type ErrorKind string
+
+const (
+	KindNone        ErrorKind = ""             // no error
+	KindInvalidURL  ErrorKind = "invalid_url"  // the website couldn't be turned into a request
+	KindDNS         ErrorKind = "dns"          // the host name couldn't be resolved
+	KindTimeout     ErrorKind = "timeout"      // a deadline (per request or overall) was exceeded
+	KindCanceled    ErrorKind = "canceled"     // the context was cancelled by the caller
+	KindTLS         ErrorKind = "tls"          // the TLS handshake or the certificate verification failed
+	KindConnection  ErrorKind = "connection"   // connecting failed or the connection broke (refused, reset, EOF, etc.)
+	KindHTTP        ErrorKind = "http"         // the server answered with a 4xx or 5xx status
+	KindCircuitOpen ErrorKind = "circuit_open" // the request wasn't made as the circuit breaker of the host is open
+	KindRedirects   ErrorKind = "redirects"    // the request was redirected more than Fetcher.MaxRedirects times
+	KindOther       ErrorKind = "other"        // none of the above
+)
+
+// StatusError is the error of a Result whose response has a 4xx or 5xx status.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %s", e.Status)
+}
+
+// classify returns the ErrorKind of an error returned by http.Client.Do or by reading a response body.
+// The order of the checks matters, e.g. a DNS lookup can time out too but "dns" tells more about what went wrong.
+func classify(err error) ErrorKind {
+	var (
+		dnsErr      *net.DNSError
+		statusErr   *StatusError
+		netErr      net.Error
+		recordErr   tls.RecordHeaderError
+		certErr     *tls.CertificateVerificationError
+		unknownAuth x509.UnknownAuthorityError
+		hostErr     x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		opErr       *net.OpError
+		urlErr      *url.Error
+	)
+	switch {
+	case err == nil:
+		return KindNone
+	case errors.As(err, &statusErr):
+		return KindHTTP
+	case errors.Is(err, context.Canceled):
+		return KindCanceled
+	case errors.Is(err, ErrTooManyRedirects):
+		return KindRedirects
+	case errors.As(err, &dnsErr):
+		return KindDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout
+	case errors.As(err, &recordErr), errors.As(err, &certErr), errors.As(err, &unknownAuth),
+		errors.As(err, &hostErr), errors.As(err, &invalidCert):
+		return KindTLS
+	case errors.As(err, &opErr), errors.Is(err, net.ErrClosed),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return KindConnection
+	case errors.As(err, &urlErr) && urlErr.Op == "parse":
+		return KindInvalidURL
+	}
+	return KindOther
+}
@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestErrorKinds makes every kind of failure happen against a local stand-in and checks how it is classified.
+func TestErrorKinds(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") })
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) })
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "oops", http.StatusInternalServerError) })
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/loop", http.StatusFound) })
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tlsSrv := httptest.NewUnstartedServer(mux)
+	tlsSrv.Config.ErrorLog = log.New(io.Discard, "", 0) // the handshakes failing on purpose would be logged
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	// A port nobody listens on: listening and closing right away frees it.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := "http://" + l.Addr().String()
+	l.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		fetcher Fetcher
+		ctx     context.Context
+		website string
+		want    ErrorKind
+		wantErr error // checked with errors.Is when set
+	}{
+		{name: "ok", website: srv.URL + "/ok", want: KindNone},
+		{name: "dns", website: "http://no-such-host.invalid/", want: KindDNS},
+		{name: "refused", website: refused, want: KindConnection},
+		{name: "timeout", fetcher: Fetcher{Timeout: 50 * time.Millisecond}, website: srv.URL + "/slow", want: KindTimeout, wantErr: context.DeadlineExceeded},
+		{name: "canceled", ctx: canceled, website: srv.URL + "/ok", want: KindCanceled, wantErr: context.Canceled},
+		{name: "untrusted certificate", fetcher: Fetcher{Client: &http.Client{}}, website: tlsSrv.URL + "/ok", want: KindTLS},
+		{name: "4xx", website: srv.URL + "/missing", want: KindHTTP},
+		{name: "5xx", website: srv.URL + "/broken", want: KindHTTP},
+		{name: "invalid url", website: "http://[::1", want: KindInvalidURL},
+		{name: "too many redirects", fetcher: Fetcher{MaxRedirects: 2}, website: srv.URL + "/loop", want: KindRedirects, wantErr: ErrTooManyRedirects},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			res := tt.fetcher.Fetch(ctx, tt.website)
+			if res.Kind != tt.want {
+				t.Errorf("Kind = %q (error %v), want %q", res.Kind, res.Err, tt.want)
+			}
+			if (res.Err == nil) != (tt.want == KindNone) {
+				t.Errorf("Err = %v with Kind %q", res.Err, res.Kind)
+			}
+			if tt.wantErr != nil && !errors.Is(res.Err, tt.wantErr) {
+				t.Errorf("Err = %v, want it to wrap %v", res.Err, tt.wantErr)
+			}
+			if res.Kind == KindHTTP {
+				var statusErr *StatusError
+				if !errors.As(res.Err, &statusErr) || statusErr.Code != res.StatusCode {
+					t.Errorf("Err = %v, want a *StatusError with code %d", res.Err, res.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorKindCircuitOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	f := Fetcher{Breaker: &Breaker{Threshold: 1, Cooldown: time.Hour}}
+	if res := f.Fetch(context.Background(), srv.URL); res.Kind != KindHTTP || res.Circuit != CircuitOpen {
+		t.Fatalf("first request = %q with circuit %q, want http with the circuit open", res.Kind, res.Circuit)
+	}
+	res := f.Fetch(context.Background(), srv.URL)
+	if res.Kind != KindCircuitOpen || !errors.Is(res.Err, ErrCircuitOpen) {
+		t.Errorf("request with the circuit open = %v (%s), want %v", res.Err, res.Kind, ErrCircuitOpen)
+	}
+}
+
+func TestClassifyWrapped(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{nil, KindNone},
+		{fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), KindConnection},
+		{fmt.Errorf("reading body: %w", net.ErrClosed), KindConnection},
+		{&net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}, KindDNS},
+		{errors.New("something else"), KindOther},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
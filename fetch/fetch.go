@@ -0,0 +1,311 @@
+// Package fetch fetches websites concurrently for the concurrency lesson.
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/pool"
+)
+
+// Result is what fetching a website gave, sent on the channel of FetchAll.
+// When there was no response at all (Err is set and StatusCode is 0), only Website, Err, Kind, Elapsed, Attempts and Redirects are set.
+type Result struct {
+	Website string
+	// URL is the URL of the final response, after following the Redirects.
+	URL        string
+	Status     string // e.g. "200 OK", empty if there was no response
+	StatusCode int
+	Header     http.Header
+	// Body is the body read, nil with Fetcher.DiscardBody.
+	Body []byte
+	// BodySize is the number of bytes of the body read, at most Fetcher.MaxBodySize.
+	BodySize int64
+	// Truncated reports that the body was longer than Fetcher.MaxBodySize, and that only the start of it was read.
+	Truncated bool
+	// ContentHash is the hex encoded SHA-256 of the body read (of its start if Truncated), empty if the body couldn't be read.
+	ContentHash string
+	// Err is nil on success, a *StatusError for 4xx and 5xx statuses.
+	Err  error
+	Kind ErrorKind
+	// Elapsed is the time taken by all the attempts and the waits between them.
+	Elapsed time.Duration
+	// Phases breaks the time taken by the last attempt down into DNS, connect, TLS, time to first byte and transfer.
+	// After redirects, they are those of the final request, while Elapsed covers all of them.
+	Phases Phases
+	// Attempts holds every request made for the website, the last one being the one this Result is about.
+	Attempts []Attempt
+	// Circuit is the state of the circuit of the website's host after the last attempt, empty without a Fetcher.Breaker.
+	Circuit CircuitState
+	// Redirects are the responses redirecting from Website to URL, in order, empty without redirects.
+	Redirects []Redirect
+	// TLS describes the TLS connection of the final response, nil for http://.
+	TLS *TLSInfo
+	// Warnings are problems which don't fail the request, like a certificate expiring soon.
+	Warnings []string
+}
+
+// OK reports whether the website was fetched with a status below 400.
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Fetcher fetches websites, cancelling the requests when their context is done.
+// The zero value uses http.DefaultClient without a per request timeout.
+type Fetcher struct {
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Timeout limits every request including reading its body, no limit if 0.
+	// The overall deadline is set by the context passed to Fetch or FetchAll.
+	Timeout time.Duration
+	// Workers bounds the number of concurrent requests made by FetchAll, a goroutine per website if 0.
+	Workers int
+	// Ordered makes FetchAll send the results in the order of its websites, only used with Workers.
+	Ordered bool
+	// Retry decides whether and when failed requests are retried, the zero value never retries.
+	Retry RetryPolicy
+	// Observer is told about every request made, including every retry, nil for none.
+	Observer Observer
+	// Limiter is waited on before every request (including every retry), nil for none, e.g. a *ratelimit.Limiter.
+	Limiter Limiter
+	// Breaker fails the requests to a host which keeps failing without making them, nil for none.
+	Breaker *Breaker
+	// MaxBodySize is the number of bytes read from a body at most, the rest is left unread and the Result is Truncated.
+	// DefaultMaxBodySize if 0, no limit if negative.
+	MaxBodySize int64
+	// DiscardBody only counts and hashes the body while reading it, without keeping it in memory.
+	DiscardBody bool
+	// MaxRedirects is the number of redirects followed at most, the request fails with ErrTooManyRedirects after that.
+	// 10 if 0, negative to not follow redirects at all (the redirect is then the Result).
+	MaxRedirects int
+	// CertExpiryWarning adds a warning to the Results whose certificate expires within that time, none if 0.
+	CertExpiryWarning time.Duration
+	// RequestHeader returns extra headers for the requests to website, e.g. the conditional headers of the changes package, nil for none.
+	RequestHeader func(website string) http.Header
+}
+
+// DefaultMaxBodySize is the MaxBodySize of a Fetcher leaving it at 0, large enough for any ordinary page.
+const DefaultMaxBodySize = 10 << 20 // 10 MiB
+
+// Limiter paces the requests to a host, Wait blocks until a request to host may be made or returns an error if ctx is done first.
+type Limiter interface {
+	Wait(ctx context.Context, host string) error
+}
+
+// Observer is notified before and after every request, e.g. to count them (see the metrics package).
+// Its methods are called from the goroutines of FetchAll, so they must be safe for concurrent use.
+type Observer interface {
+	RequestStarted(website string)
+	// RequestDone gets the Result of the single request, without Attempts.
+	RequestDone(res Result)
+	// RequestSkipped gets the Result of an attempt which failed without making a request (an invalid URL, an open circuit or a limiter wait cut short),
+	// so that the errors can be counted without counting a request nor its time. RequestStarted and RequestDone aren't called for it.
+	RequestSkipped(res Result)
+}
+
+func (f *Fetcher) client() *http.Client {
+	if f.Client == nil {
+		return http.DefaultClient
+	}
+	return f.Client
+}
+
+// Fetch fetches website and returns its Result, whose Err tells if the request failed or ctx was done first.
+// Failed requests are retried according to f.Retry, every attempt is recorded in Result.Attempts.
+func (f *Fetcher) Fetch(ctx context.Context, website string) Result {
+	start := time.Now()
+	var attempts []Attempt
+	var wait time.Duration
+	for n := 1; ; n++ {
+		res := f.fetchOnce(ctx, website)
+		attempts = append(attempts, Attempt{Status: res.Status, Err: res.Err, Kind: res.Kind, Elapsed: res.Elapsed, Phases: res.Phases, Wait: wait})
+
+		var retry bool
+		wait, retry = f.Retry.next(ctx, n, res)
+		if !retry || !sleep(ctx, wait) {
+			res.Attempts = attempts
+			res.Elapsed = time.Since(start)
+			return res
+		}
+	}
+}
+
+// fetchOnce makes a single request, unless the circuit of the host is open.
+// It waits for f.Limiter first, and tells f.Observer and f.Breaker about the request.
+func (f *Fetcher) fetchOnce(ctx context.Context, website string) Result {
+	// An invalid URL has no host, do reports it without making a request.
+	u, err := url.Parse(website)
+	if err != nil {
+		return f.notMade(f.do(ctx, website))
+	}
+	if f.Breaker != nil {
+		// Checked before the limiter, a request which won't be made shouldn't wait for (nor take) a token.
+		if state, ok := f.Breaker.allow(u.Host); !ok {
+			return f.notMade(Result{Website: website, Err: ErrCircuitOpen, Kind: KindCircuitOpen, Circuit: state})
+		}
+	}
+	if f.Limiter != nil {
+		start := time.Now()
+		if err := f.Limiter.Wait(ctx, u.Host); err != nil {
+			res := Result{Website: website, Err: err, Kind: classify(err), Elapsed: time.Since(start)}
+			if f.Breaker != nil {
+				// Frees the probe slot taken by allow, a cancelled request doesn't count as a failure.
+				res.Circuit = f.Breaker.done(u.Host, Result{Kind: KindCanceled})
+			}
+			return f.notMade(res)
+		}
+	}
+
+	if f.Observer != nil {
+		f.Observer.RequestStarted(website)
+	}
+	res := f.do(ctx, website)
+	if f.Observer != nil {
+		f.Observer.RequestDone(res)
+	}
+	if f.Breaker != nil {
+		res.Circuit = f.Breaker.done(u.Host, res)
+	}
+	return res
+}
+
+// notMade tells f.Observer about an attempt which failed before making a request, and returns its Result.
+func (f *Fetcher) notMade(res Result) Result {
+	if f.Observer != nil {
+		f.Observer.RequestSkipped(res)
+	}
+	return res
+}
+
+// do makes a single request, limited by f.Timeout.
+// The response is only read from after checking err, as it is nil whenever err isn't.
+func (f *Fetcher) do(ctx context.Context, website string) Result {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	ctx, trace := withTrace(ctx)
+	start := trace.start
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, website, nil)
+	if err != nil {
+		return Result{Website: website, Err: err, Kind: KindInvalidURL}
+	}
+	if f.RequestHeader != nil {
+		for key, values := range f.RequestHeader(website) {
+			req.Header[key] = values
+		}
+	}
+	var hops []Redirect
+	res, err := f.followingClient(&hops, trace.reset).Do(req)
+	if err != nil {
+		// There is no response (and no status) when the request itself failed, the phases show how far it got.
+		end := time.Now()
+		return Result{Website: website, Err: err, Kind: classify(err), Elapsed: end.Sub(start), Phases: trace.phases(end), Redirects: hops}
+	}
+	// Closing the body right away with defer covers every return below, reading it fully or not.
+	defer res.Body.Close()
+	r := Result{Website: website, URL: res.Request.URL.String(), Status: res.Status, StatusCode: res.StatusCode, Header: res.Header,
+		Redirects: hops, TLS: newTLSInfo(res.TLS)}
+	if warning := r.TLS.expiryWarning(f.CertExpiryWarning, time.Now()); warning != "" {
+		r.Warnings = append(r.Warnings, warning)
+	}
+
+	// Reading the body is cancelled along with ctx too, as the body is read from the same connection.
+	err = f.readBody(&r, res)
+	end := time.Now()
+	r.Elapsed, r.Phases = end.Sub(start), trace.phases(end)
+	if err != nil {
+		r.Err, r.Kind = err, classify(err)
+		return r
+	}
+	if res.StatusCode >= 400 {
+		r.Err, r.Kind = &StatusError{Code: res.StatusCode, Status: res.Status}, KindHTTP
+	}
+	return r
+}
+
+// readBody streams the body of res into a SHA-256 hash (and a buffer unless f.DiscardBody), stopping after f.MaxBodySize bytes.
+// Unlike io.ReadAll, the memory used is bounded by the limit however large the body is, and with DiscardBody it is the same for any body.
+func (f *Fetcher) readBody(r *Result, res *http.Response) error {
+	limit := f.MaxBodySize
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	hash := sha256.New()
+	var dst io.Writer = hash
+	var buf *bytes.Buffer
+	if !f.DiscardBody {
+		buf = &bytes.Buffer{}
+		if res.ContentLength > 0 && (limit < 0 || res.ContentLength <= limit) {
+			buf.Grow(int(res.ContentLength)) // saves growing the buffer again and again while reading
+		}
+		dst = io.MultiWriter(hash, buf)
+	}
+	src := io.Reader(res.Body)
+	if limit > 0 {
+		src = io.LimitReader(res.Body, limit)
+	}
+
+	n, err := io.Copy(dst, src)
+	r.BodySize = n
+	if buf != nil {
+		r.Body = buf.Bytes()
+	}
+	if err != nil {
+		return err
+	}
+	if limit > 0 && n == limit {
+		// Reading a single byte more tells whether the body was longer than the limit, it isn't hashed nor kept.
+		var extra [1]byte
+		if m, _ := io.ReadFull(res.Body, extra[:]); m > 0 {
+			r.Truncated = true
+		}
+	}
+	r.ContentHash = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// FetchAll fetches every website and sends the results on the returned channel in the order they finish (or in order of websites if f.Ordered).
+// Every website gets its own goroutine, unless f.Workers bounds them using a pool.
+// The channel is closed once all the goroutines have returned.
+// It is buffered for all the results, so the goroutines never block on sending, and they all return even if the caller stops receiving.
+// Cancel ctx to give up: the requests still in flight fail with the context's error and their results are sent as usual.
+// With f.Workers, the websites not yet handed to a worker are dropped instead.
+func (f *Fetcher) FetchAll(ctx context.Context, websites []string) <-chan Result {
+	results := make(chan Result, len(websites))
+	if f.Workers > 0 {
+		pooled := pool.Run(ctx, pool.Options{Workers: f.Workers, Queue: f.Workers, Ordered: f.Ordered}, websites, f.Fetch)
+		go func() {
+			defer close(results)
+			for r := range pooled {
+				results <- r
+			}
+		}()
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for _, website := range websites {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- f.Fetch(ctx, website)
+		}()
+	}
+	// The sender closes the channel, once it knows nothing else will be sent.
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
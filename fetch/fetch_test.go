@@ -0,0 +1,218 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+// hangingServer never answers, its handlers return once the client gives up (or the server is closed).
+func hangingServer() *httptest.Server {
+	release := make(chan struct{})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	srv.Config.RegisterOnShutdown(func() { close(release) })
+	srv.Start()
+	return srv
+}
+
+func TestFetchTimeout(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := hangingServer()
+	defer srv.Close()
+
+	f := Fetcher{Client: srv.Client(), Timeout: 50 * time.Millisecond, Retry: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}
+	start := time.Now()
+	res := f.Fetch(context.Background(), srv.URL)
+	if res.Kind != KindTimeout || !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("Fetch of a hanging server = %v (%s), want a timeout", res.Err, res.Kind)
+	}
+	if len(res.Attempts) != 2 {
+		t.Errorf("got %d attempts, want 2 as timeouts are retried", len(res.Attempts))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Fetch took %s with a timeout of 50ms per request", elapsed)
+	}
+}
+
+func TestFetchOverallDeadline(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := hangingServer()
+	defer srv.Close()
+
+	// No timeout per request, and retries which would go on for long: only the deadline of ctx stops them.
+	f := Fetcher{Client: srv.Client(), Retry: RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res := f.Fetch(ctx, srv.URL)
+	if res.Kind != KindTimeout {
+		t.Errorf("Fetch past the deadline of ctx = %v (%s), want a timeout", res.Err, res.Kind)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Fetch took %s with an overall deadline of 100ms", elapsed)
+	}
+}
+
+func TestFetchAllCancel(t *testing.T) {
+	for _, workers := range []int{0, 2} {
+		t.Run(fmt.Sprintf("workers-%d", workers), func(t *testing.T) {
+			defer leak.Verify(t)()
+			srv := hangingServer()
+			defer srv.Close()
+
+			websites := make([]string, 5)
+			for i := range websites {
+				websites[i] = fmt.Sprintf("%s/%d", srv.URL, i)
+			}
+			f := Fetcher{Client: srv.Client(), Workers: workers}
+			ctx, cancel := context.WithCancel(context.Background())
+			results := f.FetchAll(ctx, websites)
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			// The channel must be closed soon after cancelling, whatever the requests were doing.
+			timeout := time.After(2 * time.Second)
+			n := 0
+			for {
+				select {
+				case res, ok := <-results:
+					if !ok {
+						if n == 0 || n > len(websites) {
+							t.Errorf("got %d results for %d websites", n, len(websites))
+						}
+						return
+					}
+					n++
+					if res.Kind != KindCanceled {
+						t.Errorf("result of %s = %v (%s), want canceled", res.Website, res.Err, res.Kind)
+					}
+				case <-timeout:
+					t.Fatal("FetchAll didn't close its channel after ctx was cancelled")
+				}
+			}
+		})
+	}
+}
+
+// TestFetchAllStopReceiving checks that no goroutine of FetchAll is left blocked when the caller stops receiving after the first result.
+func TestFetchAllStopReceiving(t *testing.T) {
+	defer leak.Verify(t)()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }))
+	defer srv.Close()
+
+	for _, workers := range []int{0, 2} {
+		f := Fetcher{Client: srv.Client(), Workers: workers}
+		ctx, cancel := context.WithCancel(context.Background())
+		res := <-f.FetchAll(ctx, []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"})
+		cancel()
+		if !res.OK() {
+			t.Errorf("workers %d: first result = %v", workers, res.Err)
+		}
+	}
+}
+
+// recorder is an Observer recording its calls, e.g. "started", "done 200" or "skipped circuit_open".
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) RequestStarted(string)     { r.record("started") }
+func (r *recorder) RequestDone(res Result)    { r.record(fmt.Sprint("done ", res.StatusCode)) }
+func (r *recorder) RequestSkipped(res Result) { r.record(fmt.Sprint("skipped ", res.Kind)) }
+
+// limiterFunc makes a function a Limiter.
+type limiterFunc func(ctx context.Context, host string) error
+
+func (f limiterFunc) Wait(ctx context.Context, host string) error { return f(ctx, host) }
+
+// TestObserver checks that the attempts failing without a request are only passed to RequestSkipped, so they aren't counted as requests.
+func TestObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	f := Fetcher{Client: srv.Client(), Observer: rec, Breaker: &Breaker{Threshold: 1}}
+	f.Fetch(context.Background(), srv.URL) // opens the circuit
+	f.Fetch(context.Background(), srv.URL)
+	f.Fetch(context.Background(), "http://[::1")
+	f.Limiter = limiterFunc(func(context.Context, string) error { return context.Canceled })
+	f.Breaker = nil
+	f.Fetch(context.Background(), srv.URL)
+
+	want := []string{"started", "done 503", "skipped circuit_open", "skipped invalid_url", "skipped canceled"}
+	if !slices.Equal(rec.calls, want) {
+		t.Errorf("Observer calls = %q, want %q", rec.calls, want)
+	}
+}
+
+// BenchmarkFetchAll fetches a list of URLs from a local test server, so it measures the overhead of the fan-out and not the network.
+// Every op fetches all the URLs, with either a goroutine per URL or a bounded pool of workers:
+//
+//	go test -bench FetchAll ./fetch
+func BenchmarkFetchAll(b *testing.B) {
+	b.Run("unbounded", func(b *testing.B) { benchmarkFetchAll(b, 0) })
+	for _, workers := range []int{8, 64, 256} {
+		b.Run(fmt.Sprintf("pool-%d", workers), func(b *testing.B) { benchmarkFetchAll(b, workers) })
+	}
+	b.Run("pool-64-ordered", func(b *testing.B) {
+		benchmarkFetchAll(b, 64, func(f *Fetcher) { f.Ordered = true })
+	})
+}
+
+const benchURLs = 1000
+
+func benchmarkFetchAll(b *testing.B, workers int, opts ...func(*Fetcher)) {
+	// Deferred first so that it runs last, once the server and the connections are closed: whatever still runs then was leaked by FetchAll.
+	defer leak.Verify(b)()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond) // some latency, like a real server
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	urls := make([]string, benchURLs)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/page/%d", srv.URL, i)
+	}
+	// The default transport only keeps 2 idle connections per host, which would make every run open new connections.
+	transport := &http.Transport{MaxIdleConnsPerHost: 256}
+	defer transport.CloseIdleConnections()
+	f := Fetcher{Client: &http.Client{Transport: transport}, Timeout: 10 * time.Second, Workers: workers}
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	failed := 0
+	for range b.N {
+		for r := range f.FetchAll(context.Background(), urls) {
+			if !r.OK() {
+				failed++
+			}
+		}
+	}
+	// Too many concurrent connections make requests fail (e.g. running out of file descriptors), which is why the fan-out needs a bound.
+	b.ReportMetric(float64(failed)/float64(b.N), "failed/op")
+}
@@ -0,0 +1,50 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTooManyRedirects is wrapped by the error of a Result whose request was redirected more than Fetcher.MaxRedirects times.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// Redirect is a hop of a redirect chain: a response redirecting to another URL.
+type Redirect struct {
+	URL        string // the URL which answered with the redirect
+	Status     string // e.g. "301 Moved Permanently"
+	StatusCode int
+}
+
+// followingClient returns a copy of the client recording every redirect into hops, and stopping after f.MaxRedirects of them.
+// follow is called before every redirect followed, i.e. before the next request is made.
+// The copy shares the Transport (and so the connections) of the client, only its CheckRedirect differs.
+func (f *Fetcher) followingClient(hops *[]Redirect, follow func()) *http.Client {
+	base := f.client()
+	c := *base
+	limit := f.MaxRedirects
+	if limit == 0 {
+		limit = 10
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if limit < 0 {
+			// The redirect response is returned as the Result itself.
+			return http.ErrUseLastResponse
+		}
+		// req.Response is the response redirecting to req.
+		prev := req.Response
+		*hops = append(*hops, Redirect{URL: prev.Request.URL.String(), Status: prev.Status, StatusCode: prev.StatusCode})
+		// via holds the requests made so far, one more than the redirects followed.
+		if len(via) > limit {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, limit)
+		}
+		if base.CheckRedirect != nil {
+			if err := base.CheckRedirect(req, via); err != nil {
+				return err
+			}
+		}
+		follow()
+		return nil
+	}
+	return &c
+}
@@ -0,0 +1,88 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// chainServer redirects /hop/n to /hop/n-1 down to /hop/0, which answers "done".
+func chainServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if n == 0 {
+			fmt.Fprint(w, "done")
+			return
+		}
+		http.Redirect(w, r, "/hop/"+strconv.Itoa(n-1), http.StatusFound)
+	}))
+}
+
+func TestMaxRedirects(t *testing.T) {
+	srv := chainServer()
+	defer srv.Close()
+	tests := []struct {
+		name         string
+		max, chain   int
+		wantErr      bool
+		wantStatus   int
+		wantURL      string
+		wantRedirect int
+	}{
+		{"within the default of 10", 0, 10, false, 200, "/hop/0", 10},
+		{"past the default of 10", 0, 11, true, 0, "", 11},
+		{"exactly the limit", 2, 2, false, 200, "/hop/0", 2},
+		{"one past the limit", 2, 3, true, 0, "", 3},
+		{"no redirect with a limit", 2, 0, false, 200, "/hop/0", 0},
+		// A negative limit follows no redirect: the redirect itself is the Result, and not an error.
+		{"negative", -1, 3, false, 302, "/hop/3", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fetcher{Client: srv.Client(), MaxRedirects: tt.max}
+			res := f.Fetch(context.Background(), srv.URL+"/hop/"+strconv.Itoa(tt.chain))
+			if gotErr := res.Err != nil; gotErr != tt.wantErr {
+				t.Fatalf("Err = %v, want an error: %v", res.Err, tt.wantErr)
+			}
+			if tt.wantErr && (res.Kind != KindRedirects || !errors.Is(res.Err, ErrTooManyRedirects)) {
+				t.Errorf("Err = %v of kind %q, want ErrTooManyRedirects of kind %q", res.Err, res.Kind, KindRedirects)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantURL != "" && res.URL != srv.URL+tt.wantURL {
+				t.Errorf("URL = %s, want %s", res.URL, srv.URL+tt.wantURL)
+			}
+			if len(res.Redirects) != tt.wantRedirect {
+				t.Errorf("%d redirects recorded, want %d", len(res.Redirects), tt.wantRedirect)
+			}
+		})
+	}
+}
+
+func TestRedirectChain(t *testing.T) {
+	srv := chainServer()
+	defer srv.Close()
+	f := &Fetcher{Client: srv.Client()}
+	res := f.Fetch(context.Background(), srv.URL+"/hop/2")
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := []Redirect{
+		{URL: srv.URL + "/hop/2", Status: "302 Found", StatusCode: 302},
+		{URL: srv.URL + "/hop/1", Status: "302 Found", StatusCode: 302},
+	}
+	if len(res.Redirects) != len(want) {
+		t.Fatalf("Redirects = %v, want %v", res.Redirects, want)
+	}
+	for i := range want {
+		if res.Redirects[i] != want[i] {
+			t.Errorf("Redirects[%d] = %v, want %v", i, res.Redirects[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package fetch
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Attempt is the outcome of a single request made by Fetcher.Fetch.
+type Attempt struct {
+	Status  string // empty if there was no response
+	Err     error  // nil if the attempt succeeded
+	Kind    ErrorKind
+	Elapsed time.Duration
+	Phases  Phases
+	Wait    time.Duration // waited before making this attempt
+}
+
+// DefaultRetryOn are the statuses retried when RetryPolicy.RetryOn is nil: too many requests and the gateway errors, which are usually temporary.
+var DefaultRetryOn = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// RetryPolicy decides whether a failed request is retried and how long to wait before retrying it.
+// Requests failing without a response (connection refused, per request timeout, etc.) and responses with a RetryOn status are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of requests made in total, including the first one. 0 or 1 never retries.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the wait before the first retry, doubled for every further retry (100ms if 0).
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential growth of the wait (10s if 0).
+	// A Retry-After longer than MaxDelay ends the retries, as the server asked to come back much later.
+	MaxDelay time.Duration
+	// RetryOn are the HTTP statuses worth retrying, DefaultRetryOn if nil.
+	RetryOn []int
+}
+
+// Backoff returns the wait before retry number `retry` (starting at 1) using "full jitter":
+// a random duration between 0 and min(MaxDelay, BaseDelay * 2^(retry-1)).
+// The randomness spreads the retries of many clients over time, instead of all of them retrying in the same instant.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	base, maxDelay := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+	ceiling := maxDelay
+	// Shifting by 62 or more would overflow, and the ceiling is way past any sensible MaxDelay long before that.
+	if shift := retry - 1; shift < 62 && base<<shift > 0 && base<<shift < maxDelay {
+		ceiling = base << shift
+	}
+	return rand.N(ceiling + 1)
+}
+
+// next returns the wait before the next attempt after attempt number n, and whether to retry at all.
+// Invalid URLs and redirect loops are never retried, as they fail the same way however many times they are retried, nor are requests refused by an open circuit.
+func (p RetryPolicy) next(ctx context.Context, n int, res Result) (time.Duration, bool) {
+	if n >= p.MaxAttempts || res.Err == nil || ctx.Err() != nil {
+		return 0, false
+	}
+	switch res.Kind {
+	case KindInvalidURL, KindRedirects, KindCircuitOpen:
+		return 0, false
+	}
+	if res.StatusCode != 0 {
+		retryOn := p.RetryOn
+		if retryOn == nil {
+			retryOn = DefaultRetryOn
+		}
+		if !slices.Contains(retryOn, res.StatusCode) {
+			return 0, false
+		}
+	}
+
+	wait := p.Backoff(n)
+	if d, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+		maxDelay := p.MaxDelay
+		if maxDelay <= 0 {
+			maxDelay = 10 * time.Second
+		}
+		if d > maxDelay {
+			return 0, false
+		}
+		wait = d
+	}
+	// No point waiting past the overall deadline, the retry would fail straight away.
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,187 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		retry   int
+		ceiling time.Duration
+	}{
+		{"first retry", RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 1, 10 * time.Millisecond},
+		{"doubled", RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, 3, 40 * time.Millisecond},
+		{"capped by MaxDelay", RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}, 5, 50 * time.Millisecond},
+		{"defaults", RetryPolicy{}, 2, 200 * time.Millisecond},
+		{"default cap", RetryPolicy{}, 20, 10 * time.Second},
+		// The shift would overflow, the ceiling stays at MaxDelay.
+		{"huge retry", RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}, 100, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Full jitter is random, enough draws must land both in the lower and the upper half of [0, ceiling].
+			var low, high bool
+			for range 1000 {
+				d := tt.policy.Backoff(tt.retry)
+				if d < 0 || d > tt.ceiling {
+					t.Fatalf("Backoff(%d) = %s, want within [0, %s]", tt.retry, d, tt.ceiling)
+				}
+				low = low || d < tt.ceiling/2
+				high = high || d > tt.ceiling/2
+			}
+			if !low || !high {
+				t.Errorf("Backoff(%d) isn't spread over [0, %s]: below half %t, above half %t", tt.retry, tt.ceiling, low, high)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		// A date in the past means the server is ready now.
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"1.5", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %t, want %s, %t", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// statusResult is the Result of a request answered with code, and a Retry-After header unless it is empty.
+func statusResult(code int, retryAfter string) Result {
+	res := Result{StatusCode: code, Status: http.StatusText(code), Header: http.Header{}}
+	if code >= 400 {
+		res.Err, res.Kind = &StatusError{Code: code, Status: res.Status}, KindHTTP
+	}
+	if retryAfter != "" {
+		res.Header.Set("Retry-After", retryAfter)
+	}
+	return res
+}
+
+func TestNext(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		name  string
+		n     int
+		res   Result
+		retry bool
+		wait  time.Duration // the exact wait if set, else the backoff is checked to be at most BaseDelay
+	}{
+		{"503", 1, statusResult(http.StatusServiceUnavailable, ""), true, 0},
+		{"429", 1, statusResult(http.StatusTooManyRequests, ""), true, 0},
+		{"404", 1, statusResult(http.StatusNotFound, ""), false, 0},
+		{"500 isn't in the default set", 1, statusResult(http.StatusInternalServerError, ""), false, 0},
+		{"success", 1, statusResult(http.StatusOK, ""), false, 0},
+		{"no response", 1, Result{Err: context.DeadlineExceeded, Kind: KindTimeout}, true, 0},
+		{"last attempt", 3, statusResult(http.StatusServiceUnavailable, ""), false, 0},
+		{"invalid URL", 1, Result{Err: errors.New("missing protocol scheme"), Kind: KindInvalidURL}, false, 0},
+		{"open circuit", 1, Result{Err: ErrCircuitOpen, Kind: KindCircuitOpen}, false, 0},
+		{"Retry-After", 1, statusResult(http.StatusTooManyRequests, "3"), true, 3 * time.Second},
+		{"Retry-After past MaxDelay", 1, statusResult(http.StatusServiceUnavailable, "11"), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, retry := policy.next(context.Background(), tt.n, tt.res)
+			if retry != tt.retry {
+				t.Fatalf("next(%d, %v) retries: %t, want %t", tt.n, tt.res.Err, retry, tt.retry)
+			}
+			switch {
+			case !retry && wait != 0:
+				t.Errorf("next waits %s without retrying", wait)
+			case retry && tt.wait != 0 && wait != tt.wait:
+				t.Errorf("next waits %s, want the Retry-After of %s", wait, tt.wait)
+			case retry && tt.wait == 0 && wait > policy.BaseDelay:
+				t.Errorf("next waits %s, want at most BaseDelay %s", wait, policy.BaseDelay)
+			}
+		})
+	}
+
+	t.Run("custom RetryOn", func(t *testing.T) {
+		p := policy
+		p.RetryOn = []int{http.StatusInternalServerError}
+		if _, retry := p.next(context.Background(), 1, statusResult(http.StatusInternalServerError, "")); !retry {
+			t.Error("500 in RetryOn isn't retried")
+		}
+		if _, retry := p.next(context.Background(), 1, statusResult(http.StatusServiceUnavailable, "")); retry {
+			t.Error("503 is retried while RetryOn only has 500")
+		}
+	})
+	t.Run("past the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, retry := policy.next(ctx, 1, statusResult(http.StatusTooManyRequests, "5")); retry {
+			t.Error("retrying after a Retry-After of 5s with 1s left")
+		}
+	})
+}
+
+// countingServer answers with the statuses in turn, the last one for every further request, and counts the requests.
+func countingServer(hits *atomic.Int32, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+}
+
+func TestMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		statuses    []int
+		wantHits    int32
+		wantOK      bool
+	}{
+		{"exhausted", 3, []int{503}, 3, false},
+		{"recovers", 3, []int{503, 502, 200}, 3, true},
+		{"recovers early", 5, []int{429, 200}, 2, true},
+		{"not retried", 3, []int{404}, 1, false},
+		{"no retries", 0, []int{503}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := countingServer(&hits, tt.statuses...)
+			defer srv.Close()
+
+			f := Fetcher{Client: srv.Client(), Retry: RetryPolicy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond}}
+			res := f.Fetch(context.Background(), srv.URL)
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server got %d requests, want %d", got, tt.wantHits)
+			}
+			if len(res.Attempts) != int(tt.wantHits) {
+				t.Errorf("got %d attempts, want one per request (%d)", len(res.Attempts), tt.wantHits)
+			}
+			if res.OK() != tt.wantOK {
+				t.Errorf("Fetch = %v, want OK: %t", res.Err, tt.wantOK)
+			}
+			for i, a := range res.Attempts[:len(res.Attempts)-1] {
+				if a.Err == nil {
+					t.Errorf("attempt %d succeeded but was retried", i+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// TLSInfo describes the TLS connection of an https:// response and the certificate of the server.
+type TLSInfo struct {
+	Version     string // e.g. "TLS 1.3"
+	CipherSuite string // e.g. "TLS_AES_128_GCM_SHA256"
+	ServerName  string
+	// Subject and Issuer are the distinguished names of the leaf certificate (the one of the server itself) and of the authority which signed it, e.g. "CN=R11,O=Let's Encrypt,C=US".
+	Subject, Issuer string
+	// NotAfter is when the leaf certificate expires.
+	NotAfter time.Time
+}
+
+// newTLSInfo returns the TLSInfo of a connection, nil if it wasn't a TLS connection.
+func newTLSInfo(cs *tls.ConnectionState) *TLSInfo {
+	if cs == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		Version:     tls.VersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ServerName:  cs.ServerName,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		leaf := cs.PeerCertificates[0]
+		info.Subject, info.Issuer, info.NotAfter = leaf.Subject.String(), leaf.Issuer.String(), leaf.NotAfter
+	}
+	return info
+}
+
+// expiryWarning returns a warning if the leaf certificate expires within d of now, "" otherwise.
+func (t *TLSInfo) expiryWarning(d time.Duration, now time.Time) string {
+	if t == nil || t.NotAfter.IsZero() || d <= 0 {
+		return ""
+	}
+	left := t.NotAfter.Sub(now)
+	if left >= d {
+		return ""
+	}
+	if left < 0 {
+		return fmt.Sprintf("certificate expired on %s", t.NotAfter.Format(time.DateOnly))
+	}
+	return fmt.Sprintf("certificate expires in %d days, on %s", int(left.Hours()/24), t.NotAfter.Format(time.DateOnly))
+}
@@ -0,0 +1,65 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryWarning(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	info := &TLSInfo{NotAfter: now.Add(10 * 24 * time.Hour)}
+	tests := []struct {
+		name string
+		info *TLSInfo
+		d    time.Duration
+		want string
+	}{
+		{"no TLS", nil, 30 * 24 * time.Hour, ""},
+		{"no certificate", &TLSInfo{}, 30 * 24 * time.Hour, ""},
+		{"no warning asked for", info, 0, ""},
+		{"expires later", info, 10 * 24 * time.Hour, ""},
+		{"expires within", info, 10*24*time.Hour + 1, "certificate expires in 10 days, on 2024-01-11"},
+		{"expires today", &TLSInfo{NotAfter: now.Add(time.Hour)}, 24 * time.Hour, "certificate expires in 0 days, on 2024-01-01"},
+		{"expired", &TLSInfo{NotAfter: now.Add(-48 * time.Hour)}, 24 * time.Hour, "certificate expired on 2023-12-30"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.expiryWarning(tt.d, now); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTLSInfo(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	leaf := srv.Certificate()
+	left := time.Until(leaf.NotAfter)
+
+	f := &Fetcher{Client: srv.Client(), CertExpiryWarning: left + 24*time.Hour}
+	res := f.Fetch(context.Background(), srv.URL)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.TLS == nil || !res.TLS.NotAfter.Equal(leaf.NotAfter) || res.TLS.Subject != leaf.Subject.String() || !strings.HasPrefix(res.TLS.Version, "TLS 1.") {
+		t.Errorf("TLS = %+v, want the details of the test server's certificate", res.TLS)
+	}
+	if len(res.Warnings) != 1 || !strings.HasPrefix(res.Warnings[0], "certificate expires in ") {
+		t.Errorf("Warnings = %q, want the certificate expiring within CertExpiryWarning", res.Warnings)
+	}
+
+	f.CertExpiryWarning = left - 24*time.Hour
+	if res := f.Fetch(context.Background(), srv.URL); len(res.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none for a certificate expiring later", res.Warnings)
+	}
+
+	// Plain http has no TLS, and so nothing to warn about.
+	plain := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer plain.Close()
+	if res := (&Fetcher{CertExpiryWarning: time.Hour}).Fetch(context.Background(), plain.URL); res.TLS != nil || len(res.Warnings) != 0 {
+		t.Errorf("http://: TLS = %+v, Warnings = %q", res.TLS, res.Warnings)
+	}
+}
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Phases breaks the time taken by a request down into its phases, measured using net/http/httptrace.
+// When redirects were followed, they are the phases of the final request, the one answering with the Result's response.
+// DNS, Connect and TLS are 0 when an idle connection was reused (Reused), or when the phase didn't happen (no TLS for http://, no DNS for IP addresses).
+type Phases struct {
+	DNS     time.Duration // resolving the host name
+	Connect time.Duration // opening the TCP connection
+	TLS     time.Duration // the TLS handshake
+	// Wait is the time the server took to answer: from the request being written to the first byte of the response.
+	Wait time.Duration
+	// TTFB (time to first byte) is the time from the start of the request to the first byte of the response, so it includes all the phases above.
+	TTFB time.Duration
+	// Transfer is the time taken by reading the body after the first byte.
+	Transfer time.Duration
+	Reused   bool
+}
+
+func (p Phases) String() string {
+	ms := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+	if p.Reused {
+		return fmt.Sprintf("reused connection, wait=%s ttfb=%s transfer=%s", ms(p.Wait), ms(p.TTFB), ms(p.Transfer))
+	}
+	return fmt.Sprintf("dns=%s connect=%s tls=%s wait=%s ttfb=%s transfer=%s",
+		ms(p.DNS), ms(p.Connect), ms(p.TLS), ms(p.Wait), ms(p.TTFB), ms(p.Transfer))
+}
+
+// tracer records the instants of the httptrace hooks of a single request.
+// The hooks can be called from other goroutines than the one making the request (e.g. dialing happens in its own goroutine), hence the mutex.
+type tracer struct {
+	mu                        sync.Mutex
+	start                     time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	reused                    bool
+}
+
+// withTrace returns ctx carrying the hooks of a new tracer, started now.
+func withTrace(ctx context.Context) (context.Context, *tracer) {
+	t := &tracer{start: time.Now()}
+	set := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// Only the first call counts, e.g. ConnectStart is called for every address tried.
+		if at.IsZero() {
+			*at = time.Now()
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart:      func(string, string) { set(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart: func() { set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}), t
+}
+
+// reset forgets the instants recorded so far and starts again now, when a redirect is followed: the Phases are those of the final request.
+// Otherwise the DNS and connect times would be those of the first hop, while the first byte and Reused would be those of the last one.
+func (t *tracer) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.start = time.Now()
+	t.dnsStart, t.dnsDone, t.connectStart, t.connectDone = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone, t.wroteRequest, t.firstByte = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	t.reused = false
+}
+
+// phases returns the Phases of the request, whose body was read completely at end.
+func (t *tracer) phases(end time.Time) Phases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	between := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+	return Phases{
+		DNS:      between(t.dnsStart, t.dnsDone),
+		Connect:  between(t.connectStart, t.connectDone),
+		TLS:      between(t.tlsStart, t.tlsDone),
+		Wait:     between(t.wroteRequest, t.firstByte),
+		TTFB:     between(t.start, t.firstByte),
+		Transfer: between(t.firstByte, end),
+		Reused:   t.reused,
+	}
+}
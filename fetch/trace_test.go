@@ -0,0 +1,53 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPhasesAfterRedirect checks that the phases are those of the final request when /a redirects to a slow /b.
+func TestPhasesAfterRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusFound) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var f Fetcher
+	res := f.Fetch(context.Background(), srv.URL+"/a")
+	if !res.OK() || len(res.Redirects) != 1 {
+		t.Fatalf("Fetch = %v with %d redirects, want 1", res.Err, len(res.Redirects))
+	}
+	p := res.Phases
+	if p.Wait < 150*time.Millisecond {
+		t.Errorf("Wait = %s, want the 200ms of /b (phases %s)", p.Wait, p)
+	}
+	if p.TTFB < p.Wait || p.TTFB > res.Elapsed {
+		t.Errorf("TTFB = %s, want between Wait (%s) and Elapsed (%s)", p.TTFB, p.Wait, res.Elapsed)
+	}
+	if p.Transfer > 100*time.Millisecond {
+		t.Errorf("Transfer = %s, want only the reading of the body of /b", p.Transfer)
+	}
+	if !p.Reused {
+		t.Errorf("Reused = false, /b should reuse the connection of /a (phases %s)", p)
+	}
+}
+
+func TestPhasesNewConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }))
+	defer srv.Close()
+
+	// A client of its own, so that no idle connection can be reused.
+	f := Fetcher{Client: &http.Client{Transport: &http.Transport{}}}
+	res := f.Fetch(context.Background(), srv.URL)
+	if p := res.Phases; p.Reused || p.Connect <= 0 || p.TTFB < p.Connect {
+		t.Errorf("phases of a request on a new connection = %+v", p)
+	}
+}
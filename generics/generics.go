@@ -0,0 +1,76 @@
+// Package generics holds the generic functions and constraints used by the generics lesson.
+// Generics allow you to write functions and data structures that can work with any data type.
+package generics
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Concatable is satisfied by every type having a `String() string` method.
+type Concatable interface {
+	String() string
+}
+
+// Comparable is satisfied by strings, ints and float64s, and by every type whose underlying type is one of them (because of `~`).
+// Unlike the builtin `comparable`, this is a type set and can only be used as a constraint.
+type Comparable interface {
+	~string | ~int | ~float64
+}
+
+// Parameterized interfaces - Life
+// Individual satisfies ProperNoun because it has Name() string.
+// CalAge satisfies Life[Individual] because it implements Action(Individual).
+type Life[T ProperNoun] interface {
+	Action(T)
+}
+
+// ProperNoun is anything having a name.
+type ProperNoun interface {
+	Name() string
+}
+
+// Individual is a ProperNoun with a birth year.
+type Individual struct {
+	name      string
+	birthYear int
+}
+
+// NewIndividual returns an Individual, needed because its fields are unexported.
+func NewIndividual(name string, birthYear int) Individual {
+	return Individual{name: name, birthYear: birthYear}
+}
+
+func (p Individual) Name() string {
+	return p.name
+}
+
+// CalAge calculates the age of an Individual in its Action method.
+type CalAge struct {
+	age int
+}
+
+func (a *CalAge) Action(p Individual) {
+	a.age = time.Now().Year() - p.birthYear
+}
+
+// Age returns the age calculated by the last Action.
+func (a *CalAge) Age() int {
+	return a.age
+}
+
+// Print writes any value to w using the `%v` format specifier.
+func Print[T any](w io.Writer, value T) {
+	fmt.Fprintf(w, "%v\n", value)
+}
+
+// Concat concatenates the string forms of a and b.
+func Concat[T Concatable](a, b T) string {
+	return a.String() + b.String()
+}
+
+// Compare reports whether a and b are equal.
+func Compare[T Comparable](a, b T) bool {
+	return a == b
+}
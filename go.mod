@@ -0,0 +1,3 @@
+module github.com/ISanviI/LearnGo
+
+go 1.23.1
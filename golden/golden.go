@@ -0,0 +1,144 @@
+// Package golden compares the output of a program with a golden file, which holds the output that is known to be correct.
+// Parts of the output that change on every run (pointer addresses, map iteration order, timings) are normalised before comparing.
+package golden
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Normalizer rewrites the non-deterministic parts of an output so that two runs of the same program give the same text.
+type Normalizer func(string) string
+
+// Replace returns a Normalizer replacing every match of the regular expression pattern with repl.
+// repl can use `$1` etc. to refer to the submatches like in regexp.ReplaceAllString.
+func Replace(pattern, repl string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return func(s string) string {
+		return re.ReplaceAllString(s, repl)
+	}
+}
+
+// SortRuns returns a Normalizer sorting every run of consecutive lines matching the regular expression pattern.
+// Useful for lines printed while ranging over a map, or by goroutines racing with each other.
+func SortRuns(pattern string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return func(s string) string {
+		lines := strings.Split(s, "\n")
+		for i := 0; i < len(lines); {
+			if !re.MatchString(lines[i]) {
+				i++
+				continue
+			}
+			j := i
+			for j < len(lines) && re.MatchString(lines[j]) {
+				j++
+			}
+			sort.Strings(lines[i:j])
+			i = j
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// Common normalisers, the replacement text is kept short and obviously fake so that it is not mistaken for real output.
+var (
+	// Addresses hides pointer addresses printed using `%p` or Println.
+	Addresses = Replace(`0x[0-9a-f]+`, "0xADDR")
+	// Durations hides timings printed as "<n> ms" or using time.Duration's String method.
+	Durations = Replace(`\b\d+(\.\d+)?( ?ms|ns|µs|s)\b`, "N${2}")
+	// Timestamps hides times formatted using time.RFC3339.
+	Timestamps = Replace(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`, "TIMESTAMP")
+)
+
+// Normalize applies the normalisers to out in order.
+func Normalize(out string, normalizers ...Normalizer) string {
+	for _, n := range normalizers {
+		out = n(out)
+	}
+	return out
+}
+
+// MismatchError is returned by Check when the output differs from the golden file.
+type MismatchError struct {
+	Path string
+	Diff string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("output differs from %s (rerun with -update if the change is intended):\n%s", e.Path, e.Diff)
+}
+
+// Check compares got with the golden file at path.
+// With update set, the golden file is (re)written with got instead, creating its directory when needed.
+// A missing golden file is reported as an error wrapping fs.ErrNotExist unless update is set.
+func Check(path, got string, update bool) error {
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(got), 0o644)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading golden file (create it using -update): %w", err)
+	}
+	if string(want) == got {
+		return nil
+	}
+	return &MismatchError{Path: path, Diff: Diff(string(want), got)}
+}
+
+// Diff returns a line based diff turning want into got, prefixing removed lines with "-" and added lines with "+".
+// It uses the longest common subsequence of lines, which is quadratic but fine for the size of the lessons' outputs.
+func Diff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&sb, "%d: +%s\n", j+1, b[j])
+			j++
+		default:
+			fmt.Fprintf(&sb, "%d: -%s\n", i+1, a[i])
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// SyncWriter serialises the writes to W, so that goroutines can share an io.Writer like a bytes.Buffer which is not safe for concurrent use.
+type SyncWriter struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.W.Write(p)
+}
@@ -0,0 +1,50 @@
+package golden
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		name string
+		n    Normalizer
+		in   string
+		want string
+	}{
+		{"addresses", Addresses, "p=0xc000012345 q=0x1f", "p=0xADDR q=0xADDR"},
+		{"durations", Durations, "took 1.5s, 20 ms, 3ms, 12µs and 7ns", "took Ns, N ms, Nms, Nµs and Nns"},
+		{"timestamps", Timestamps, "at 2024-05-01T10:20:30Z or 2024-05-01T10:20:30.123+02:00", "at TIMESTAMP or TIMESTAMP"},
+		{"replace with submatch", Replace(`id=(\w)\d+`, "id=${1}N"), "id=a12 id=b3", "id=aN id=bN"},
+		{"sort runs", SortRuns(`^k`), "start\nk3\nk1\nk2\nmiddle\nk9\nk0", "start\nk1\nk2\nk3\nmiddle\nk0\nk9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in, tt.n); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "lesson.golden")
+	if err := Check(path, "a\nb\n", false); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Check of a missing file = %v, want fs.ErrNotExist", err)
+	}
+	if err := Check(path, "a\nb\n", true); err != nil {
+		t.Fatalf("Check with update: %v", err)
+	}
+	if err := Check(path, "a\nb\n", false); err != nil {
+		t.Errorf("Check of the same output: %v", err)
+	}
+	var mismatch *MismatchError
+	if err := Check(path, "a\nc\n", false); !errors.As(err, &mismatch) {
+		t.Fatalf("Check of a different output = %v, want a *MismatchError", err)
+	}
+	if want := "2: +c\n2: -b\n"; mismatch.Diff != want {
+		t.Errorf("Diff = %q, want %q", mismatch.Diff, want)
+	}
+}
@@ -0,0 +1,173 @@
+// Package leak finds goroutine leaks: goroutines started by some code which are still running after it returned, usually blocked forever on a channel or a lock.
+//
+// Take a Snapshot before running the code and call Check (or Leaked) on it afterwards, or use Verify in a test:
+//
+//	defer leak.Verify(t)()
+package leak
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Goroutine is a goroutine parsed from the output of runtime.Stack.
+type Goroutine struct {
+	ID int
+	// State is what the goroutine is doing, e.g. "chan send" or "sync.Mutex.Lock", with how long it has been blocked when it is long enough.
+	State string
+	// Function is the innermost function of the stack outside the runtime, internal and sync packages: where the goroutine is stuck, e.g. main.worker rather than runtime.gopark.
+	Function string
+	// Entry is the function the goroutine was started with, the bottom of its stack.
+	Entry string
+	// Stack is the full stack trace, as printed by a panic.
+	Stack string
+}
+
+func (g Goroutine) String() string {
+	return fmt.Sprintf("goroutine %d [%s] in %s", g.ID, g.State, g.Function)
+}
+
+// Snapshot holds the goroutines running at some point.
+type Snapshot struct {
+	ids map[int]bool
+}
+
+// Take returns a Snapshot of the goroutines running now.
+func Take() *Snapshot {
+	s := &Snapshot{ids: map[int]bool{}}
+	for _, g := range All() {
+		s.ids[g.ID] = true
+	}
+	return s
+}
+
+// DefaultWait is how long Check waits for the new goroutines to return.
+// Goroutines often return a moment after the code which started them (a deferred wg.Done, a connection noticing it was closed), which isn't a leak.
+const DefaultWait = time.Second
+
+// Leaked returns the goroutines started since the snapshot which are still running after waiting up to wait for them to return.
+// Goroutines whose Entry contains any of ignore (e.g. "net/http.(*persistConn)" for the goroutines of keep-alive connections) are left out.
+func (s *Snapshot) Leaked(wait time.Duration, ignore ...string) []Goroutine {
+	deadline := time.Now().Add(wait)
+	for delay := time.Millisecond; ; delay = min(2*delay, 100*time.Millisecond) {
+		var leaked []Goroutine
+		for _, g := range All() {
+			if !s.ids[g.ID] && !ignored(g, ignore) {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(delay)
+	}
+}
+
+func ignored(g Goroutine, ignore []string) bool {
+	return slices.ContainsFunc(ignore, func(s string) bool { return strings.Contains(g.Entry, s) })
+}
+
+// Check returns a *Error listing the goroutines leaked since the snapshot, nil if there are none, waiting up to DefaultWait for them to return.
+func (s *Snapshot) Check(ignore ...string) error {
+	if leaked := s.Leaked(DefaultWait, ignore...); len(leaked) > 0 {
+		return &Error{Goroutines: leaked}
+	}
+	return nil
+}
+
+// Error reports leaked goroutines along with their stacks, which show where they are stuck.
+type Error struct {
+	Goroutines []Goroutine
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d leaked goroutine(s):", len(e.Goroutines))
+	for _, g := range e.Goroutines {
+		fmt.Fprintf(&sb, "\n\n%s", g.Stack)
+	}
+	return sb.String()
+}
+
+// TB is the part of testing.TB Verify needs, so that the package doesn't import testing.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Verify takes a snapshot and returns a function failing t if goroutines leaked in between, to be deferred at the start of a test or benchmark.
+func Verify(t TB, ignore ...string) func() {
+	s := Take()
+	return func() {
+		t.Helper()
+		if err := s.Check(ignore...); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
+
+// All returns the goroutines running now, the calling one included.
+func All() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parse(string(buf[:n]))
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parse parses the output of runtime.Stack, a block per goroutine separated by blank lines:
+//
+//	goroutine 7 [chan send, 2 minutes]:
+//	main.worker(0xc000012345)
+//		/path/to/main.go:12 +0x25
+//	created by main.main in goroutine 1
+//		/path/to/main.go:20 +0x3c
+func parse(stacks string) []Goroutine {
+	var gs []Goroutine
+	for _, block := range strings.Split(strings.TrimSpace(stacks), "\n\n") {
+		header, rest, _ := strings.Cut(block, "\n")
+		idAndState, ok := strings.CutPrefix(header, "goroutine ")
+		if !ok {
+			continue
+		}
+		idStr, state, _ := strings.Cut(idAndState, " ")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		state = strings.TrimSuffix(strings.TrimPrefix(state, "["), "]:")
+		g := Goroutine{ID: id, State: state, Stack: block}
+		// Every frame is a line with the function and its arguments followed by a line with its file, indented.
+		for _, line := range strings.Split(rest, "\n") {
+			if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "created by ") {
+				continue
+			}
+			if i := strings.LastIndexByte(line, '('); i > 0 {
+				line = line[:i] // the arguments
+			}
+			if g.Function == "" && !internal(line) {
+				g.Function = line
+			}
+			g.Entry = line
+		}
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+// internal tells whether function belongs to the packages a goroutine blocks in, rather than to the code which blocked it.
+func internal(function string) bool {
+	for _, prefix := range []string{"runtime.", "internal/", "sync."} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package leak
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dump is the output of runtime.Stack(buf, true) for a program with a worker stuck on a channel.
+const dump = `goroutine 1 [running]:
+main.main()
+	/home/me/app/main.go:30 +0x1d4
+
+goroutine 7 [chan receive, 2 minutes]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:402 +0xce
+runtime.chanrecv(0xc000020180, 0x0, 0x1)
+	/usr/local/go/src/runtime/chan.go:583 +0x3bf
+runtime.chanrecv1(0x0?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:442 +0x12
+main.worker(...)
+	/home/me/app/main.go:12
+main.start.func1()
+	/home/me/app/main.go:20 +0x25
+created by main.start in goroutine 1
+	/home/me/app/main.go:18 +0x3c
+
+goroutine 9 [sync.Mutex.Lock]:
+sync.runtime_SemacquireMutex(0xc000012345?, 0x0?, 0x1?)
+	/usr/local/go/src/runtime/sema.go:77 +0x25
+sync.(*Mutex).lockSlow(0xc0000a2010)
+	/usr/local/go/src/sync/mutex.go:171 +0x15d
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:90
+net/http.(*persistConn).readLoop(0xc0000b4000)
+	/usr/local/go/src/net/http/transport.go:2205 +0x1a5
+created by net/http.(*Transport).dialConn in goroutine 6
+	/usr/local/go/src/net/http/transport.go:1799 +0x152f
+
+goroutine 12 [select]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:402 +0xce
+runtime.selectgo(0xc000049f40, 0xc000049f1c, 0x0?, 0x0, 0x0?, 0x1)
+	/usr/local/go/src/runtime/select.go:327 +0x725
+created by time.goFunc
+	/usr/local/go/src/time/sleep.go:177 +0x2d
+`
+
+func TestParse(t *testing.T) {
+	got := parse(dump)
+	want := []Goroutine{
+		{ID: 1, State: "running", Function: "main.main", Entry: "main.main"},
+		// The "created by" line tells who started the goroutine, not what it runs, so Entry is the bottom frame.
+		{ID: 7, State: "chan receive, 2 minutes", Function: "main.worker", Entry: "main.start.func1"},
+		{ID: 9, State: "sync.Mutex.Lock", Function: "net/http.(*persistConn).readLoop", Entry: "net/http.(*persistConn).readLoop"},
+		// Only runtime frames: no Function to blame.
+		{ID: 12, State: "select", Function: "", Entry: "runtime.selectgo"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsed %d goroutines, want %d: %v", len(got), len(want), got)
+	}
+	for i, g := range got {
+		if !strings.HasPrefix(g.Stack, fmt.Sprintf("goroutine %d [", want[i].ID)) {
+			t.Errorf("goroutine %d: Stack starts with %q", want[i].ID, strings.SplitN(g.Stack, "\n", 2)[0])
+		}
+		g.Stack = ""
+		if g != want[i] {
+			t.Errorf("goroutine %d = %+v, want %+v", want[i].ID, g, want[i])
+		}
+	}
+
+	// Blocks which aren't goroutines are skipped.
+	if gs := parse("\n\nnot a goroutine\n\ngoroutine x [running]:\nmain.main()\n"); len(gs) != 0 {
+		t.Errorf("parse of garbage = %v, want none", gs)
+	}
+}
+
+func TestAll(t *testing.T) {
+	gs := All()
+	i := slices.IndexFunc(gs, func(g Goroutine) bool { return strings.Contains(g.Stack, "leak.TestAll(") })
+	if i < 0 {
+		t.Fatalf("the calling goroutine isn't in All(): %v", gs)
+	}
+	// All itself is the innermost frame outside the runtime of the goroutine calling it.
+	if g := gs[i]; g.State != "running" || g.Function != "github.com/ISanviI/LearnGo/leak.All" || g.Entry != "testing.tRunner" {
+		t.Errorf("calling goroutine = %v entered in %s, want running in leak.All, entered in testing.tRunner", g, g.Entry)
+	}
+}
+
+// blockOn blocks until ch is closed, a goroutine leaks when it is never closed.
+func blockOn(ch chan struct{}) {
+	<-ch
+}
+
+func TestLeaked(t *testing.T) {
+	s := Take()
+	ch := make(chan struct{})
+	go blockOn(ch)
+
+	leaked := s.Leaked(50 * time.Millisecond)
+	if len(leaked) != 1 {
+		t.Fatalf("Leaked = %v, want the blocked goroutine", leaked)
+	}
+	if g := leaked[0]; g.Function != "github.com/ISanviI/LearnGo/leak.blockOn" || !strings.HasPrefix(g.State, "chan receive") {
+		t.Errorf("leaked goroutine = %v, want blocked on chan receive in blockOn", g)
+	}
+	if leaked := s.Leaked(0, "leak.blockOn"); len(leaked) != 0 {
+		t.Errorf("Leaked ignoring leak.blockOn = %v, want none", leaked)
+	}
+	if leaked := s.Leaked(0, "leak.other", "net/http"); len(leaked) != 1 {
+		t.Errorf("Leaked ignoring other functions = %v, want the blocked goroutine", leaked)
+	}
+
+	// Once released, it returns within the wait and isn't a leak anymore.
+	close(ch)
+	if leaked := s.Leaked(DefaultWait); len(leaked) != 0 {
+		t.Errorf("Leaked after releasing the goroutine = %v, want none", leaked)
+	}
+}
+
+func TestLeakedWaits(t *testing.T) {
+	s := Take()
+	ch := make(chan struct{})
+	go blockOn(ch)
+	time.AfterFunc(20*time.Millisecond, func() { close(ch) })
+
+	// The goroutine is still running when Leaked starts, but returns long before the wait is over.
+	start := time.Now()
+	if leaked := s.Leaked(DefaultWait); len(leaked) != 0 {
+		t.Errorf("Leaked = %v, want none as the goroutine returns within the wait", leaked)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultWait {
+		t.Errorf("Leaked returned after %s, want as soon as the goroutine returned", elapsed)
+	}
+}
+
+// fakeTB records the errors reported through it.
+type fakeTB struct {
+	errors []string
+}
+
+func (*fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerify(t *testing.T) {
+	var clean fakeTB
+	Verify(&clean)()
+	if len(clean.errors) != 0 {
+		t.Errorf("Verify without a leak failed: %q", clean.errors)
+	}
+
+	var tb fakeTB
+	check := Verify(&tb)
+	ch := make(chan struct{})
+	defer close(ch)
+	go blockOn(ch)
+	check()
+	if len(tb.errors) != 1 {
+		t.Fatalf("Verify reported %d errors, want 1 for the blocked goroutine", len(tb.errors))
+	}
+	if msg := tb.errors[0]; !strings.HasPrefix(msg, "1 leaked goroutine(s):") || !strings.Contains(msg, "leak.blockOn") {
+		t.Errorf("Verify error = %q, want the stack of the goroutine blocked in blockOn", msg)
+	}
+
+	var ignoring fakeTB
+	check = Verify(&ignoring, "leak.blockOn")
+	ch2 := make(chan struct{})
+	defer close(ch2)
+	go blockOn(ch2)
+	check()
+	if len(ignoring.errors) != 0 {
+		t.Errorf("Verify ignoring leak.blockOn failed: %q", ignoring.errors)
+	}
+}
@@ -0,0 +1,123 @@
+package lockcheck
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ISanviI/LearnGo/stats"
+)
+
+// Contention tells how long the goroutines waited for a lock and how long they held it once they had it.
+// Long waits with short holds mean many goroutines want the lock at once (shard it, or use atomics), long holds mean too much is done under it.
+// The count, the total, the minimum and the maximum are exact, the percentiles are estimated from a sample of up to maxSamples times.
+type Contention struct {
+	Name string
+	// Waits summarises the time from calling Lock (or RLock) to getting the lock, Waits.Count is the number of times the lock was taken and released.
+	Waits stats.Summary
+	// Holds summarises the time from getting the lock to releasing it. Read locks held at the same time are counted separately.
+	Holds stats.Summary
+}
+
+type lockTimes struct {
+	waits, holds reservoir
+}
+
+// maxSamples bounds the times kept per lock, a lock taken millions of times would otherwise keep millions of them.
+const maxSamples = 1024
+
+// reservoir keeps a uniform sample of up to maxSamples of the times added to it (reservoir sampling):
+// the n-th time replaces a random one of the sample with a probability of maxSamples/n, so every time added has the same chance of being in it.
+type reservoir struct {
+	n             int
+	sum, min, max time.Duration
+	samples       []time.Duration
+}
+
+func (r *reservoir) add(d time.Duration) {
+	r.n++
+	r.sum += d
+	if r.n == 1 || d < r.min {
+		r.min = d
+	}
+	r.max = max(r.max, d)
+	if len(r.samples) < maxSamples {
+		r.samples = append(r.samples, d)
+	} else if i := rand.IntN(r.n); i < maxSamples {
+		r.samples[i] = d
+	}
+}
+
+// summary returns the exact count, sum, mean, minimum and maximum, and the percentiles of the sample.
+func (r *reservoir) summary() stats.Summary {
+	// Only the percentiles and the totals make sense here, there is no wall clock time of "the run".
+	s := stats.Summarize(r.samples, 0)
+	if r.n > 0 {
+		s.Count, s.Sum, s.Min, s.Max, s.Mean = r.n, r.sum, r.min, r.max, r.sum/time.Duration(r.n)
+	}
+	return s
+}
+
+// lockTimes returns the times of lock name, d.mu must be held.
+func (d *Detector) lockTimes(name string) *lockTimes {
+	if d.times == nil {
+		d.times = map[string]*lockTimes{}
+	}
+	t := d.times[name]
+	if t == nil {
+		t = &lockTimes{}
+		d.times[name] = t
+	}
+	return t
+}
+
+// Contention returns the wait and hold times of every lock recorded so far (with RecordTimes), the most waited for first.
+// The times are recorded when a lock is released, so a lock held right now isn't counted yet.
+func (d *Detector) Contention() []Contention {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cs := make([]Contention, 0, len(d.times))
+	for name, t := range d.times {
+		cs = append(cs, Contention{Name: name, Waits: t.waits.summary(), Holds: t.holds.summary()})
+	}
+	slices.SortFunc(cs, func(a, b Contention) int {
+		return cmp.Or(cmp.Compare(b.Waits.Sum, a.Waits.Sum), cmp.Compare(a.Name, b.Name))
+	})
+	return cs
+}
+
+// WriteContention writes the Contention of every lock as a table.
+func (d *Detector) WriteContention(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCK\tCOUNT\tWAIT TOTAL\tWAIT P99\tHOLD TOTAL\tHOLD P99")
+	for _, c := range d.Contention() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", c.Name, c.Waits.Count, c.Waits.Sum, c.Waits.P99, c.Holds.Sum, c.Holds.P99)
+	}
+	return tw.Flush()
+}
+
+// MutexProfile turns on the mutex profile of the runtime, which samples 1 in fraction contention events of every sync.Mutex and sync.RWMutex of the program,
+// instrumented or not, with the stack of where the waiting goroutine was released. fraction 1 records every event.
+// It returns a function writing the profile to path, for `go tool pprof <path>`, and turning the profile off again.
+func MutexProfile(fraction int) (write func(path string) error) {
+	previous := runtime.SetMutexProfileFraction(fraction)
+	return func(path string) error {
+		defer runtime.SetMutexProfileFraction(previous)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := pprof.Lookup("mutex").WriteTo(f, 0); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+}
@@ -0,0 +1,62 @@
+package lockcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContention(t *testing.T) {
+	d := &Detector{RecordTimes: true}
+	m := &Mutex{Name: "m", Detector: d}
+	m.Lock()
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		m.Lock() // waits for the 50ms hold below
+		m.Unlock()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	m.Unlock()
+	<-waited
+	// A lock still held has no times yet.
+	(&Mutex{Name: "other", Detector: d}).Lock()
+
+	cs := d.Contention()
+	if len(cs) != 1 || cs[0].Name != "m" {
+		t.Fatalf("Contention = %+v, want the times of m only: other was never released", cs)
+	}
+	c := cs[0]
+	if c.Waits.Count != 2 || c.Holds.Count != 2 {
+		t.Errorf("counts = %d waits and %d holds, want 2 of each", c.Waits.Count, c.Holds.Count)
+	}
+	if c.Waits.Max < 40*time.Millisecond || c.Waits.Min > 10*time.Millisecond {
+		t.Errorf("waits = %+v, want one of about 50ms and one of about 0", c.Waits)
+	}
+	if c.Holds.Max < 50*time.Millisecond || c.Holds.Min > 10*time.Millisecond {
+		t.Errorf("holds = %+v, want one of at least 50ms and one of about 0", c.Holds)
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	var r reservoir
+	const n = 100 * maxSamples
+	for i := 1; i <= n; i++ {
+		r.add(time.Duration(i))
+	}
+	if len(r.samples) != maxSamples {
+		t.Errorf("%d samples kept, want %d", len(r.samples), maxSamples)
+	}
+	s := r.summary()
+	if s.Count != n || s.Sum != n*(n+1)/2 || s.Min != 1 || s.Max != n || s.Mean != (n+1)/2 {
+		t.Errorf("summary = %+v, want the exact count, sum, min, max and mean", s)
+	}
+	// The sample is uniform, so its median is close to the median of all the times.
+	if s.P50 < n*4/10 || s.P50 > n*6/10 {
+		t.Errorf("p50 = %d, want about %d", s.P50, n/2)
+	}
+
+	var empty reservoir
+	if s := empty.summary(); s.Count != 0 || s.Sum != 0 {
+		t.Errorf("summary of nothing = %+v", s)
+	}
+}
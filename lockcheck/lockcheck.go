@@ -0,0 +1,261 @@
+// Package lockcheck finds lock-order inversions and locks held too long, using Mutex and RWMutex as drop-in replacements of the ones of the sync package.
+//
+// Two goroutines deadlock when the first holds lock A and waits for B while the second holds B and waits for A.
+// That only happens when their timing is just wrong, but it can be predicted from the order the locks are taken in:
+// every time a goroutine takes a lock while holding others, the Detector adds an edge "held -> taken" to a graph of the locks,
+// and a cycle in that graph is a potential deadlock, reported even if the goroutines never actually deadlocked.
+//
+// Read locks share an RWMutex, so two goroutines taking read locks in opposite orders don't wait for each other.
+// Every edge records whether it is exclusive (one of its two locks is a write lock), and a cycle is only reported when at least one of its edges is.
+package lockcheck
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind is the kind of problem a Report is about.
+type Kind string
+
+const (
+	Cycle    Kind = "cycle"     // the locks were taken in orders which can deadlock
+	LongHold Kind = "long_hold" // a lock was held longer than Detector.HoldThreshold
+)
+
+// Report is a problem found by a Detector.
+type Report struct {
+	Kind Kind
+	// Locks are the names of the locks of the cycle, starting and ending with the same lock, or the name of the lock held too long.
+	Locks []string
+	// Held is how long the lock was held, for a LongHold.
+	Held time.Duration
+	// Stack is the stack of the goroutine which closed the cycle or released the lock.
+	Stack string
+}
+
+func (r Report) String() string {
+	switch r.Kind {
+	case Cycle:
+		return "potential deadlock: locks taken in the order " + strings.Join(r.Locks, " -> ")
+	case LongHold:
+		return fmt.Sprintf("%s held for %s", r.Locks[0], r.Held.Round(time.Millisecond))
+	}
+	return string(r.Kind)
+}
+
+// Error holds every Report of a Detector, see Detector.Check.
+type Error struct {
+	Reports []Report
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Reports))
+	for i, r := range e.Reports {
+		lines[i] = r.String()
+	}
+	return fmt.Sprintf("lockcheck: %d problem(s): %s", len(e.Reports), strings.Join(lines, "; "))
+}
+
+// Detector records the order locks are taken in by every goroutine.
+// Its zero value is ready to use, Default is used by the locks without a Detector.
+type Detector struct {
+	// HoldThreshold reports the locks held longer than this, 0 never.
+	HoldThreshold time.Duration
+	// OnReport is called with every Report as it is found, e.g. to log it, in addition to keeping it for Reports.
+	OnReport func(Report)
+	// RecordTimes keeps how long every lock was waited for and held, see Contention.
+	RecordTimes bool
+
+	mu sync.Mutex
+	// edges[a][b] exists once a goroutine took lock b while holding lock a, it is true once one of them was a write lock.
+	edges map[string]map[string]bool
+	// held are the locks held (or waited for) by every goroutine, in the order they were taken.
+	held    map[int64][]*heldLock
+	reports []Report
+	// times are the wait and hold times of every lock, if RecordTimes.
+	times map[string]*lockTimes
+}
+
+// heldLock is added by acquire, before the goroutine waits for the lock.
+// Once it has the lock, the goroutine sets at and wait and then taken, without d.mu: the Detector's bookkeeping doesn't make the lock held any longer.
+// at and wait may only be read once taken is true.
+type heldLock struct {
+	name  string
+	read  bool // a read lock of an RWMutex
+	at    time.Time
+	wait  time.Duration
+	taken atomic.Bool
+}
+
+// got records that the goroutine got the lock at, having waited for it since start.
+func (h *heldLock) got(start, at time.Time) {
+	h.at, h.wait = at, at.Sub(start)
+	h.taken.Store(true)
+}
+
+// Default is the Detector of the locks whose Detector field is nil.
+var Default = &Detector{}
+
+// Reports returns the problems found so far.
+func (d *Detector) Reports() []Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Report(nil), d.reports...)
+}
+
+// Check returns an *Error holding the problems found so far, nil if there are none.
+// In a test: `if err := d.Check(); err != nil { t.Error(err) }` once the code under test is done with its locks.
+func (d *Detector) Check() error {
+	if reports := d.Reports(); len(reports) > 0 {
+		return &Error{Reports: reports}
+	}
+	return nil
+}
+
+// acquire records that the calling goroutine is about to take lock name (a read lock if read), before it blocks, so that a deadlock about to happen is reported first.
+// The caller calls got on the returned heldLock once it has the lock.
+func (d *Detector) acquire(name string, read bool) *heldLock {
+	g := goid()
+	var found []Report
+	d.mu.Lock()
+	if d.edges == nil {
+		d.edges = map[string]map[string]bool{}
+		d.held = map[int64][]*heldLock{}
+	}
+	for _, h := range d.held[g] {
+		exclusive := !h.read || !read
+		if known, ok := d.edges[h.name][name]; ok && (known || !exclusive) {
+			continue // a known edge can't close a new cycle, unless it becomes exclusive
+		}
+		if d.edges[h.name] == nil {
+			d.edges[h.name] = map[string]bool{}
+		}
+		d.edges[h.name][name] = exclusive
+		// The new edge h -> name closes a cycle if h can already be reached from name, through an exclusive edge if the new one isn't.
+		if path := d.path(name, h.name, exclusive); path != nil {
+			found = append(found, Report{Kind: Cycle, Locks: append([]string{h.name}, path...), Stack: stack()})
+		}
+	}
+	h := &heldLock{name: name, read: read}
+	d.held[g] = append(d.held[g], h)
+	d.mu.Unlock()
+	d.report(found...)
+	return h
+}
+
+// path returns the locks from one lock to another following the edges (depth first), nil if there is no way.
+// Unless exclusive is already true, only a way through at least one exclusive edge counts.
+func (d *Detector) path(from, to string, exclusive bool) []string {
+	// A lock is visited at most twice: before and after going through an exclusive edge.
+	type state struct {
+		lock      string
+		exclusive bool
+	}
+	seen := map[state]bool{}
+	var visit func(lock string, exclusive bool) []string
+	visit = func(lock string, exclusive bool) []string {
+		if lock == to && exclusive {
+			return []string{lock}
+		}
+		if seen[state{lock, exclusive}] {
+			return nil
+		}
+		seen[state{lock, exclusive}] = true
+		for next, e := range d.edges[lock] {
+			if rest := visit(next, exclusive || e); rest != nil {
+				return append([]string{lock}, rest...)
+			}
+		}
+		return nil
+	}
+	return visit(from, exclusive)
+}
+
+// release records that lock name was released at end, once it is unlocked, reporting it when it was held longer than HoldThreshold.
+func (d *Detector) release(name string, end time.Time) {
+	g := goid()
+	d.mu.Lock()
+	owner, i := g, d.find(g, name)
+	if i < 0 {
+		// Unlike in some other languages, a Go mutex may be unlocked by another goroutine than the one which locked it.
+		// The lock is already unlocked, so another goroutine may have taken it since: the holder which took it first is the one it was released by.
+		for other, locks := range d.held {
+			if j := d.find(other, name); j >= 0 && (i < 0 || locks[j].at.Before(d.held[owner][i].at)) {
+				owner, i = other, j
+			}
+		}
+	}
+	if i < 0 {
+		d.mu.Unlock()
+		return
+	}
+	h := d.held[owner][i]
+	if d.held[owner] = slices.Delete(d.held[owner], i, i+1); len(d.held[owner]) == 0 {
+		delete(d.held, owner)
+	}
+	held := end.Sub(h.at)
+	if d.RecordTimes {
+		t := d.lockTimes(name)
+		t.waits.add(h.wait)
+		t.holds.add(held)
+	}
+	d.mu.Unlock()
+	if d.HoldThreshold > 0 && held > d.HoldThreshold {
+		d.report(Report{Kind: LongHold, Locks: []string{name}, Held: held, Stack: stack()})
+	}
+}
+
+// find returns the index of the lock taken last by name among the locks held by goroutine g, -1 if it holds none, skipping the locks it is still waiting for.
+func (d *Detector) find(g int64, name string) int {
+	locks := d.held[g]
+	for i := len(locks) - 1; i >= 0; i-- {
+		if locks[i].name == name && locks[i].taken.Load() {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Detector) report(reports ...Report) {
+	if len(reports) == 0 {
+		return
+	}
+	d.mu.Lock()
+	d.reports = append(d.reports, reports...)
+	d.mu.Unlock()
+	// Called without d.mu held, so that OnReport can use the Detector (or locks of its own).
+	for _, r := range reports {
+		if d.OnReport != nil {
+			d.OnReport(r)
+		}
+	}
+}
+
+func detector(d *Detector) *Detector {
+	if d == nil {
+		return Default
+	}
+	return d
+}
+
+// goid returns the ID of the calling goroutine, parsed from the first line of its stack ("goroutine 7 [running]:").
+// Go hides goroutine IDs on purpose so that programs don't depend on them, which is fine for a debugging tool, but too slow for locks in production.
+func goid() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	id, _ := strconv.ParseInt(string(b[:bytes.IndexByte(b, ' ')]), 10, 64)
+	return id
+}
+
+func stack() string {
+	buf := make([]byte, 8<<10)
+	return string(buf[:runtime.Stack(buf, false)])
+}
@@ -0,0 +1,109 @@
+package lockcheck
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lock takes the locks in order and releases them, as a goroutine nesting them would.
+func lock(locks ...sync.Locker) {
+	for _, l := range locks {
+		l.Lock()
+	}
+	for _, l := range slices.Backward(locks) {
+		l.Unlock()
+	}
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		// run takes locks a and b of d in some orders.
+		run  func(a, b *RWMutex)
+		want []string // the locks of the cycle reported, nil for none
+	}{
+		{"same order", func(a, b *RWMutex) { lock(a, b); lock(a, b); lock(a.RLocker(), b) }, nil},
+		{"inverted write locks", func(a, b *RWMutex) { lock(a, b); lock(b, a) }, []string{"b", "a", "b"}},
+		{"inverted read locks", func(a, b *RWMutex) { lock(a.RLocker(), b.RLocker()); lock(b.RLocker(), a.RLocker()) }, nil},
+		{"a write lock on the way", func(a, b *RWMutex) { lock(a.RLocker(), b.RLocker()); lock(b, a.RLocker()) }, []string{"b", "a", "b"}},
+		{"taken for writing last", func(a, b *RWMutex) { lock(a.RLocker(), b.RLocker()); lock(b.RLocker(), a) }, []string{"b", "a", "b"}},
+		// The read-only edge a -> b is known when it becomes exclusive, which closes the cycle.
+		{"edge becoming exclusive", func(a, b *RWMutex) {
+			lock(b.RLocker(), a.RLocker())
+			lock(a.RLocker(), b.RLocker())
+			lock(a, b)
+		}, []string{"a", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Detector{}
+			tt.run(&RWMutex{Name: "a", Detector: d}, &RWMutex{Name: "b", Detector: d})
+			reports := d.Reports()
+			if tt.want == nil {
+				if len(reports) != 0 {
+					t.Errorf("reported %v, want nothing", reports)
+				}
+				return
+			}
+			if len(reports) != 1 || reports[0].Kind != Cycle || !slices.Equal(reports[0].Locks, tt.want) {
+				t.Fatalf("reported %v, want the cycle %v", reports, tt.want)
+			}
+			if reports[0].Stack == "" {
+				t.Error("the report has no stack")
+			}
+		})
+	}
+}
+
+func TestLongerCycle(t *testing.T) {
+	d := &Detector{}
+	a, b, c := &Mutex{Name: "a", Detector: d}, &Mutex{Name: "b", Detector: d}, &Mutex{Name: "c", Detector: d}
+	lock(a, b)
+	lock(b, c)
+	if err := d.Check(); err != nil {
+		t.Fatalf("Check = %v before the cycle", err)
+	}
+	lock(c, a)
+	var e *Error
+	if err := d.Check(); !errors.As(err, &e) || len(e.Reports) != 1 || !slices.Equal(e.Reports[0].Locks, []string{"c", "a", "b", "c"}) {
+		t.Errorf("Check = %v, want the cycle c -> a -> b -> c", err)
+	}
+	// A known cycle isn't reported again.
+	lock(c, a)
+	if n := len(d.Reports()); n != 1 {
+		t.Errorf("%d reports after taking the same locks again, want 1", n)
+	}
+}
+
+func TestLongHold(t *testing.T) {
+	var reported []Report
+	d := &Detector{HoldThreshold: 20 * time.Millisecond, OnReport: func(r Report) { reported = append(reported, r) }}
+	m := &Mutex{Name: "m", Detector: d}
+	lock(m)
+	m.Lock()
+	time.Sleep(30 * time.Millisecond)
+	m.Unlock()
+	if len(reported) != 1 || reported[0].Kind != LongHold || reported[0].Locks[0] != "m" || reported[0].Held < 30*time.Millisecond {
+		t.Errorf("reported %v, want m held for 30ms", reported)
+	}
+}
+
+func TestUnlockByAnotherGoroutine(t *testing.T) {
+	d := &Detector{}
+	a, b := &Mutex{Name: "a", Detector: d}, &Mutex{Name: "b", Detector: d}
+	a.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Unlock()
+	}()
+	<-done
+	// a isn't held anymore, so taking b then a is no edge a -> b.
+	lock(b, a)
+	if err := d.Check(); err != nil {
+		t.Errorf("Check = %v, want nothing", err)
+	}
+}
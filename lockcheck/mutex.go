@@ -0,0 +1,91 @@
+package lockcheck
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The locks measure the wait and the hold right around the lock of the sync package, the Detector does its bookkeeping before waiting and after unlocking.
+
+// Mutex is a sync.Mutex whose Lock and Unlock are recorded by a Detector.
+// Its zero value is an unlocked mutex recorded by Default, under a name made of its address.
+type Mutex struct {
+	// Name identifies the mutex in the reports, it must be unique per Detector.
+	Name     string
+	Detector *Detector
+	mu       sync.Mutex
+}
+
+func (m *Mutex) name() string {
+	if m.Name == "" {
+		return fmt.Sprintf("mutex@%p", m)
+	}
+	return m.Name
+}
+
+func (m *Mutex) Lock() {
+	h := detector(m.Detector).acquire(m.name(), false)
+	start := time.Now()
+	m.mu.Lock()
+	h.got(start, time.Now())
+}
+
+func (m *Mutex) Unlock() {
+	end := time.Now()
+	m.mu.Unlock()
+	detector(m.Detector).release(m.name(), end)
+}
+
+// RWMutex is a sync.RWMutex whose locks and unlocks are recorded by a Detector.
+// Its read locks are recorded as such: read locks taken in opposite orders don't make a cycle on their own, a write lock on the way does.
+// Its zero value is an unlocked mutex recorded by Default, under a name made of its address.
+type RWMutex struct {
+	// Name identifies the mutex in the reports, it must be unique per Detector.
+	Name     string
+	Detector *Detector
+	mu       sync.RWMutex
+}
+
+func (m *RWMutex) name() string {
+	if m.Name == "" {
+		return fmt.Sprintf("rwmutex@%p", m)
+	}
+	return m.Name
+}
+
+func (m *RWMutex) Lock() {
+	h := detector(m.Detector).acquire(m.name(), false)
+	start := time.Now()
+	m.mu.Lock()
+	h.got(start, time.Now())
+}
+
+func (m *RWMutex) Unlock() {
+	end := time.Now()
+	m.mu.Unlock()
+	detector(m.Detector).release(m.name(), end)
+}
+
+func (m *RWMutex) RLock() {
+	h := detector(m.Detector).acquire(m.name(), true)
+	start := time.Now()
+	m.mu.RLock()
+	h.got(start, time.Now())
+}
+
+func (m *RWMutex) RUnlock() {
+	end := time.Now()
+	m.mu.RUnlock()
+	detector(m.Detector).release(m.name(), end)
+}
+
+// RLocker returns a sync.Locker whose Lock and Unlock call RLock and RUnlock, like sync.RWMutex.RLocker.
+func (m *RWMutex) RLocker() sync.Locker {
+	return rlocker{m}
+}
+
+type rlocker struct{ m *RWMutex }
+
+func (r rlocker) Lock()   { r.m.RLock() }
+func (r rlocker) Unlock() { r.m.RUnlock() }
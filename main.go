@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ISanviI/LearnGo/golden"
+	"github.com/ISanviI/LearnGo/leak"
+	"github.com/ISanviI/LearnGo/lockcheck"
+)
+
+// Every lesson file registers its lesson in an `init()` function, so adding a new lesson only needs a new file.
+// `init()` functions of a package run before `main()`, in the order the files are presented to the compiler (sorted by file name).
+// That order puts "10.x.go" before "2.x.go", so register orders the lessons by the number their file name starts with instead.
+
+type lesson struct {
+	name    string
+	summary string
+	run     func(w io.Writer)
+	// normalize hides the parts of the output that change between runs before comparing it with the golden file.
+	normalize []golden.Normalizer
+	// skipGolden is the reason why the output can't be compared with a golden file at all, empty if it can.
+	skipGolden string
+	// order is the number the lesson's file name starts with.
+	order int
+}
+
+var lessons []*lesson
+
+// register adds a lesson, which writes all of its output to the io.Writer it is given.
+func register(name, summary string, run func(w io.Writer), normalize ...golden.Normalizer) {
+	if _, ok := findLesson(name); ok {
+		panic("lesson registered twice: " + name)
+	}
+	l := &lesson{name: name, summary: summary, run: run, normalize: normalize, order: fileOrder()}
+	// Inserted after the lessons of a lower or equal order, which keeps `learngo list` in lesson order.
+	i := sort.Search(len(lessons), func(i int) bool { return lessons[i].order > l.order })
+	lessons = slices.Insert(lessons, i, l)
+}
+
+// fileOrder returns the number the file name of the caller of register starts with, 0 if none.
+func fileOrder() int {
+	_, file, _, ok := runtime.Caller(2)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.SplitN(filepath.Base(file), ".", 2)[0])
+	return n
+}
+
+// skipGolden excludes an already registered lesson from `learngo golden`.
+func skipGolden(name, reason string) {
+	l, ok := findLesson(name)
+	if !ok {
+		panic("skipGolden: unknown lesson " + name)
+	}
+	l.skipGolden = reason
+}
+
+func findLesson(name string) (*lesson, bool) {
+	for _, l := range lessons {
+		if l.name == name {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// findLessons looks up every name first so that a typo doesn't fail halfway through a run, no names means all the lessons.
+func findLessons(names []string) ([]*lesson, error) {
+	if len(names) == 0 {
+		return lessons, nil
+	}
+	found := make([]*lesson, 0, len(names))
+	for _, name := range names {
+		l, ok := findLesson(strings.ToLower(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown lesson %q (see `learngo list`)", name)
+		}
+		found = append(found, l)
+	}
+	return found, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  learngo list                           List all the lessons")
+	fmt.Fprintln(os.Stderr, "  learngo run [flags] <lesson>...        Run one or more lessons by name (-leaks, -mutexprofile file)")
+	fmt.Fprintln(os.Stderr, "  learngo golden [-update] [lesson]...   Compare the output of the lessons with their golden files")
+	fmt.Fprintln(os.Stderr, "  learngo check [-urls file] [flags]     Check the websites listed in a file (or stdin) and write JSON Lines/CSV reports")
+	fmt.Fprintln(os.Stderr, "  learngo monitor [-urls file] [flags]   Keep checking websites, alerting when they go down or come back up")
+	fmt.Fprintln(os.Stderr, "  learngo crawl [flags] <url>            Follow the links of a website and print its graph")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "list":
+		for i, l := range lessons {
+			fmt.Printf("%d. %-12s %s\n", i+1, l.name, l.summary)
+		}
+	case "run":
+		err = runLessons(os.Stdout, args)
+	case "golden":
+		err = runGolden(os.Stdout, args)
+	case "check":
+		err = runCheck(os.Stdout, args)
+	case "monitor":
+		err = runMonitor(os.Stdout, args)
+	case "crawl":
+		err = runCrawl(os.Stdout, args)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "learngo:", err)
+		os.Exit(1)
+	}
+}
+
+func runLessons(w io.Writer, args []string) (err error) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	leaks := fs.Bool("leaks", false, "fail if a lesson leaves goroutines running, e.g. blocked forever on a channel")
+	mutexProfile := fs.String("mutexprofile", "", "write a pprof profile of the lock contention of the lessons to this file")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("run: missing lesson name")
+	}
+	toRun, err := findLessons(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *mutexProfile != "" {
+		write := lockcheck.MutexProfile(1)
+		defer func() {
+			if werr := write(*mutexProfile); werr != nil && err == nil {
+				err = fmt.Errorf("run: %w", werr)
+			}
+		}()
+	}
+	for _, l := range toRun {
+		if len(toRun) > 1 {
+			fmt.Fprintf(w, "===== %s =====\n", l.name)
+		}
+		before := leak.Take()
+		l.run(w)
+		if *leaks {
+			if err := checkLeaks(before); err != nil {
+				return fmt.Errorf("run: %s: %w", l.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLeaks returns a *leak.Error if goroutines started since before are still running.
+// The idle keep-alive connections of http.DefaultClient are kept on purpose, for the next requests, so they are closed first rather than reported.
+func checkLeaks(before *leak.Snapshot) error {
+	http.DefaultClient.CloseIdleConnections()
+	return before.Check()
+}
+
+// goldenDir holds a golden file per lesson, named <lesson>.golden.
+var goldenDir = filepath.Join("testdata", "golden")
+
+// checkGolden runs a lesson into a buffer and compares its normalised output with its golden file, or rewrites the file with update set.
+// TestGolden (`go test -run TestGolden [-update]`) calls it for every lesson, `learngo golden` is a shortcut to it.
+func checkGolden(l *lesson, dir string, update bool) error {
+	var buf bytes.Buffer
+	before := leak.Take()
+	// Goroutines of the concurrent lessons write at the same time, which a bytes.Buffer doesn't allow.
+	l.run(&golden.SyncWriter{W: &buf})
+	// A goroutine still running after its lesson returned is a bug even when the output is right, e.g. a worker blocked on a channel nobody receives from.
+	if err := checkLeaks(before); err != nil {
+		return err
+	}
+	got := golden.Normalize(buf.String(), l.normalize...)
+	return golden.Check(filepath.Join(dir, l.name+".golden"), got, update)
+}
+
+// runGolden is `learngo golden`, the same checks as TestGolden without `go test`.
+// Run it with -update after an intended change of a lesson's output, and review the diff of the golden files before committing.
+func runGolden(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("golden", flag.ExitOnError)
+	update := fs.Bool("update", false, "rewrite the golden files with the current output")
+	dir := fs.String("dir", goldenDir, "directory holding the golden files")
+	fs.Parse(args)
+
+	toCheck, err := findLessons(fs.Args())
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, l := range toCheck {
+		if l.skipGolden != "" {
+			fmt.Fprintf(w, "skip %-12s %s\n", l.name, l.skipGolden)
+			continue
+		}
+		if err := checkGolden(l, *dir, *update); err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %-12s %s\n", l.name, err)
+			continue
+		}
+		fmt.Fprintf(w, "ok   %s\n", l.name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("golden: %d lesson(s) failed", failed)
+	}
+	return nil
+}
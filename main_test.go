@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output of the lessons")
+
+// TestGolden compares the output of every lesson with its golden file, `go test -run TestGolden -update` rewrites them after an intended change.
+func TestGolden(t *testing.T) {
+	for _, l := range lessons {
+		t.Run(l.name, func(t *testing.T) {
+			if l.skipGolden != "" {
+				t.Skip(l.skipGolden)
+			}
+			if err := checkGolden(l, goldenDir, *update); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestFindLessons checks that lessons are looked up case-insensitively, and that an unknown one fails the whole lookup.
+func TestFindLessons(t *testing.T) {
+	found, err := findLessons([]string{"Basics", "mutexes"})
+	if err != nil || len(found) != 2 || found[0].name != "basics" || found[1].name != "mutexes" {
+		t.Errorf("findLessons(Basics, mutexes) = %v, %v", found, err)
+	}
+	if _, err := findLessons([]string{"basics", "nope"}); err == nil {
+		t.Error("findLessons with an unknown lesson succeeded")
+	}
+	if all, _ := findLessons(nil); len(all) != len(lessons) {
+		t.Errorf("findLessons() found %d lessons, want all %d", len(all), len(lessons))
+	}
+}
+
+// TestLessonOrder checks that the lessons are listed in the order of the numbers their files start with, 10 after 9.
+func TestLessonOrder(t *testing.T) {
+	for i := 1; i < len(lessons); i++ {
+		if lessons[i-1].order > lessons[i].order {
+			t.Errorf("lesson %s (%d) listed before %s (%d)", lessons[i-1].name, lessons[i-1].order, lessons[i].name, lessons[i].order)
+		}
+	}
+}
+
+// TestRegisterTwice checks that registering a second lesson under a taken name panics instead of shadowing the first.
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a lesson twice didn't panic")
+		}
+	}()
+	register(lessons[0].name, "again", func(io.Writer) {})
+}
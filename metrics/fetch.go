@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// FetchMetrics counts the requests of a fetch.Fetcher, set it as the Fetcher's Observer:
+//
+//	learngo_fetch_requests_total{status_class="2xx"}  requests by status class, "none" when there was no response
+//	learngo_fetch_errors_total{kind="dns"}            failed attempts by fetch.ErrorKind, including those failing without a request
+//	learngo_fetch_in_flight                           requests currently being made
+//	learngo_fetch_duration_seconds                    histogram of the time taken by the requests
+type FetchMetrics struct {
+	requests *CounterVec
+	errors   *CounterVec
+	inFlight *Gauge
+	duration *Histogram
+}
+
+// NewFetchMetrics registers the metrics of the fetcher in r.
+func NewFetchMetrics(r *Registry) *FetchMetrics {
+	return &FetchMetrics{
+		requests: r.NewCounterVec("learngo_fetch_requests_total", "Requests made, by status class.", "status_class"),
+		errors:   r.NewCounterVec("learngo_fetch_errors_total", "Failed attempts, by kind of error, including those failing without a request.", "kind"),
+		inFlight: r.NewGauge("learngo_fetch_in_flight", "Requests currently being made."),
+		duration: r.NewHistogram("learngo_fetch_duration_seconds", "Time taken by the requests, including reading the body.", nil),
+	}
+}
+
+func (m *FetchMetrics) RequestStarted(string) {
+	m.inFlight.Inc()
+}
+
+func (m *FetchMetrics) RequestDone(res fetch.Result) {
+	m.inFlight.Dec()
+	m.requests.With(statusClass(res.StatusCode)).Inc()
+	if res.Err != nil {
+		m.errors.With(string(res.Kind)).Inc()
+	}
+	m.duration.Observe(res.Elapsed.Seconds())
+}
+
+// RequestSkipped only counts the error: no request was made, so it isn't one of the requests nor of their durations.
+func (m *FetchMetrics) RequestSkipped(res fetch.Result) {
+	m.errors.With(string(res.Kind)).Inc()
+}
+
+// statusClass groups the status codes by their first digit, as a label per code would make many more series for little benefit.
+func statusClass(code int) string {
+	if code < 100 || code > 999 {
+		return "none"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+func TestFetchMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var r Registry
+	m := NewFetchMetrics(&r)
+	f := &fetch.Fetcher{Client: srv.Client(), Observer: m, Breaker: &fetch.Breaker{Threshold: 1}}
+	ctx := context.Background()
+	f.Fetch(ctx, srv.URL+"/")
+	f.Fetch(ctx, srv.URL+"/down") // opens the circuit
+	f.Fetch(ctx, srv.URL+"/down") // fails without a request
+	f.Fetch(ctx, "http://[::1")   // so does an invalid URL
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	for _, want := range []string{
+		`learngo_fetch_requests_total{status_class="2xx"} 1`,
+		`learngo_fetch_requests_total{status_class="5xx"} 1`,
+		`learngo_fetch_errors_total{kind="circuit_open"} 1`,
+		`learngo_fetch_errors_total{kind="http"} 1`,
+		`learngo_fetch_errors_total{kind="invalid_url"} 1`,
+		"learngo_fetch_in_flight 0\n",
+		// Only the two requests made were timed.
+		"learngo_fetch_duration_seconds_count 2\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	// The attempts failing without a request are errors, but not requests.
+	if strings.Contains(b.String(), `status_class="none"`) {
+		t.Errorf("attempts without a request counted as requests:\n%s", b.String())
+	}
+}
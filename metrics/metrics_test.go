@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	var r Registry
+	// Registered out of order: the output is sorted by name.
+	h := r.NewHistogram("test_duration_seconds", "Time taken.", []float64{1, 0.1, 0.5})
+	c := r.NewCounterVec("test_requests_total", "Requests\\made,\nby path.", "path", "method")
+	g := r.NewGauge("test_in_flight", "In flight.")
+
+	c.With("/a", "GET").Inc()
+	c.With("/a", "GET").Add(2)
+	c.With(`say "hi"\n`+"\n", "POST").Inc()
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2, 3} {
+		h.Observe(v)
+	}
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_duration_seconds Time taken.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 2
+test_duration_seconds_bucket{le="0.5"} 3
+test_duration_seconds_bucket{le="1"} 4
+test_duration_seconds_bucket{le="+Inf"} 6
+test_duration_seconds_sum 6.15
+test_duration_seconds_count 6
+# HELP test_in_flight In flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_requests_total Requests\\made,\nby path.
+# TYPE test_requests_total counter
+test_requests_total{path="/a",method="GET"} 3
+test_requests_total{path="say \"hi\"\\n\n",method="POST"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("WriteTo returned %d bytes, wrote %d", n, b.Len())
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	var r Registry
+	g := r.NewGauge("g", "")
+	for _, tt := range []struct {
+		v    float64
+		want string
+	}{{0, "0"}, {1.5, "1.5"}, {1e21, "1e+21"}, {-2, "-2"}} {
+		g.Set(tt.v)
+		var b strings.Builder
+		r.WriteTo(&b)
+		if got := strings.Split(b.String(), "\n")[2]; got != "g "+tt.want {
+			t.Errorf("Set(%v) is written %q, want %q", tt.v, got, "g "+tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var r Registry
+	r.NewGauge("up", "Whether the service is up.").Set(1)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "\nup 1\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) { r.NewGauge("x", ""); r.NewCounterVec("x", "") }},
+		{"negative Add", func(r *Registry) { r.NewCounterVec("x", "").With().Add(-1) }},
+		{"wrong number of label values", func(r *Registry) { r.NewCounterVec("x", "", "a", "b").With("1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("didn't panic")
+				}
+			}()
+			tt.f(&Registry{})
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a value that only goes up, like the number of requests made.
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	c.v.add(v)
+}
+
+func (c *Counter) Value() float64 { return c.v.get() }
+
+// CounterVec is a family of Counters told apart by the values of their labels, e.g. requests by status class.
+type CounterVec struct {
+	metricName, help string
+	labels           []string
+	mu               sync.Mutex
+	counters         map[string]*Counter // by the label values joined with a 0 byte
+	values           map[string][]string
+}
+
+// NewCounterVec registers a CounterVec with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{metricName: name, help: help, labels: labels, counters: map[string]*Counter{}, values: map[string][]string{}}
+	r.add(v)
+	return v
+}
+
+// With returns the Counter for the label values, given in the order of the label names, creating it on first use.
+func (v *CounterVec) With(values ...string) *Counter {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\x00")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.counters[key]
+	if !ok {
+		c = &Counter{}
+		v.counters[key] = c
+		v.values[key] = slices.Clone(values)
+	}
+	return c
+}
+
+func (v *CounterVec) name() string { return v.metricName }
+
+func (v *CounterVec) write(w io.Writer) error {
+	if err := writeHeader(w, v.metricName, v.help, "counter"); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.counters))
+	for k := range v.counters {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys) // a stable output is easier to read and to diff
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.metricName, formatLabels(v.labels, v.values[k]), formatFloat(v.counters[k].Value())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge is a value that goes up and down, like the number of requests in flight.
+type Gauge struct {
+	metricName, help string
+	v                atomicFloat
+}
+
+// NewGauge registers a Gauge.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{metricName: name, help: help}
+	r.add(g)
+	return g
+}
+
+func (g *Gauge) Inc()           { g.v.add(1) }
+func (g *Gauge) Dec()           { g.v.add(-1) }
+func (g *Gauge) Set(v float64)  { g.v.set(v) }
+func (g *Gauge) Value() float64 { return g.v.get() }
+
+func (g *Gauge) name() string { return g.metricName }
+
+func (g *Gauge) write(w io.Writer) error {
+	if err := writeHeader(w, g.metricName, g.help, "gauge"); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.Value()))
+	return err
+}
+
+// DefBuckets are the default upper bounds of the buckets of a Histogram, in seconds, suited to the latency of network requests.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observed values (e.g. latencies) in buckets, from which Prometheus estimates percentiles.
+type Histogram struct {
+	metricName, help string
+	buckets          []float64       // sorted upper bounds, +Inf is implicit
+	counts           []atomic.Uint64 // counts[i] is the number of values <= buckets[i] but > buckets[i-1], counts[len(buckets)] is for +Inf
+	sum              atomicFloat
+}
+
+// NewHistogram registers a Histogram with the given bucket upper bounds, DefBuckets if nil.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	h := &Histogram{metricName: name, help: help, buckets: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+	r.add(h)
+	return h
+}
+
+// Observe adds v to the bucket of the smallest upper bound >= v and to the sum, it is safe for concurrent use.
+// The count of values isn't kept apart, it is the total of the buckets.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.buckets, v) // the first bucket whose upper bound is >= v
+	h.counts[i].Add(1)
+	h.sum.add(v)
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w io.Writer) error {
+	if err := writeHeader(w, h.metricName, h.help, "histogram"); err != nil {
+		return err
+	}
+	// The buckets of the format are cumulative: every bucket counts all the values <= its upper bound.
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i].Load()
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.metricName, formatFloat(le), cumulative); err != nil {
+			return err
+		}
+	}
+	cumulative += h.counts[len(h.buckets)].Load()
+	_, err := fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n",
+		h.metricName, cumulative, h.metricName, formatFloat(h.sum.get()), h.metricName, cumulative)
+	return err
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/ISanviI/LearnGo/metrics"
+)
+
+// serveMetrics serves reg on http://addr/metrics in its own goroutine, until the returned function is called.
+// Listening happens before returning, so that a wrong or busy address fails the command straight away.
+func serveMetrics(addr string, reg *metrics.Registry) (stop func(), err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg.Handler())
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "metrics:", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", ln.Addr())
+	return func() { srv.Shutdown(context.Background()) }, nil
+}
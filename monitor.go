@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/metrics"
+	"github.com/ISanviI/LearnGo/monitor"
+)
+
+// runMonitor keeps checking the websites listed in a file until interrupted (Ctrl+C), alerting on every up/down transition.
+func runMonitor(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	urlsPath := fs.String("urls", "-", "file with one URL and an optional interval per line, - for stdin")
+	interval := fs.Duration("interval", 30*time.Second, "interval of the URLs without their own")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of every check")
+	window := fs.Int("window", 100, "number of latest checks the availability and latency are computed from")
+	threshold := fs.Int("threshold", 2, "consecutive checks needed to change the state of a website")
+	availability := fs.Float64("slo-availability", 0.99, "availability objective")
+	latency := fs.Duration("slo-latency", time.Second, "latency objective, 0 for none")
+	percentile := fs.Float64("slo-percentile", 95, "percentile the latency objective applies to")
+	webhook := fs.String("webhook", "", "POST the alerts as JSON to this URL")
+	alertFile := fs.String("alert-file", "", "append the alerts to this file")
+	statusEvery := fs.Duration("status-every", time.Minute, "print the status of every website this often, 0 never")
+	breaker := fs.Int("breaker", 0, "consecutive failures of a host after which its checks fail without requests until -cooldown is over, 0 for no circuit breaker")
+	cooldown := fs.Duration("cooldown", time.Minute, "time before probing a host whose circuit is open")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics on http://<addr>/metrics, e.g. localhost:9090")
+	fs.Parse(args)
+
+	in := os.Stdin
+	if *urlsPath != "-" {
+		f, err := os.Open(*urlsPath)
+		if err != nil {
+			return fmt.Errorf("monitor: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	targets, err := monitor.ReadTargets(in, *interval)
+	if err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+	// The alerts, the changes of the circuits and the statuses are written by different goroutines.
+	// Every one of them is a single Write to w, which lockedWriter serialises so that their lines (and tables) don't interleave.
+	w = &lockedWriter{w: w}
+
+	// The checks only need the status and the timings, the bodies are hashed while read and dropped.
+	fetcher := &fetch.Fetcher{Timeout: *timeout, DiscardBody: true}
+	if *breaker > 0 {
+		fetcher.Breaker = &fetch.Breaker{
+			Threshold: *breaker,
+			Cooldown:  *cooldown,
+			OnStateChange: func(host string, from, to fetch.CircuitState) {
+				fmt.Fprintf(w, "circuit of %s: %s -> %s\n", host, from, to)
+			},
+		}
+	}
+	if *metricsAddr != "" {
+		reg := &metrics.Registry{}
+		fetcher.Observer = metrics.NewFetchMetrics(reg)
+		stop, err := serveMetrics(*metricsAddr, reg)
+		if err != nil {
+			return fmt.Errorf("monitor: %w", err)
+		}
+		defer stop()
+	}
+	m := &monitor.Monitor{
+		Check:     fetcher.Fetch,
+		Window:    *window,
+		Threshold: *threshold,
+		SLO:       monitor.SLO{Availability: *availability, Latency: *latency, LatencyPercentile: *percentile},
+		Alerters:  []monitor.Alerter{&monitor.WriterAlerter{W: w}},
+		OnAlertError: func(t monitor.Transition, err error) {
+			fmt.Fprintf(os.Stderr, "monitor: alerting %s: %v\n", t.URL, err)
+		},
+	}
+	if *webhook != "" {
+		m.Alerters = append(m.Alerters, &monitor.Webhook{URL: *webhook, Client: &http.Client{Timeout: 5 * time.Second}})
+	}
+	if *alertFile != "" {
+		m.Alerters = append(m.Alerters, &monitor.FileAlerter{Path: *alertFile})
+	}
+
+	// Ctrl+C cancels ctx, which stops every goroutine of the monitor.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *statusEvery > 0 {
+		go func() {
+			tick := time.NewTicker(*statusEvery)
+			defer tick.Stop()
+			for {
+				select {
+				case <-tick.C:
+					printStatuses(w, m.Statuses())
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	fmt.Fprintf(w, "Monitoring %d websites, press Ctrl+C to stop.\n", len(targets))
+	m.Run(ctx, targets)
+	printStatuses(w, m.Statuses())
+	return nil
+}
+
+// lockedWriter lets goroutines share an io.Writer, one Write at a time.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// printStatuses writes the statuses as a table, in a single Write as the tabwriter writes a line at a time.
+func printStatuses(w io.Writer, statuses []monitor.Status) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tSTATE\tSINCE\tCHECKS\tAVAILABILITY\tLATENCY\tSLO")
+	for _, s := range statuses {
+		slo := "met"
+		if !s.MeetsSLO {
+			slo = "MISSED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f%%\t%s\t%s\n", s.URL, s.State, s.Since.Format(time.TimeOnly), s.Checks,
+			100*s.Availability, s.Latency.Round(time.Millisecond), slo)
+	}
+	tw.Flush()
+	w.Write(buf.Bytes())
+}
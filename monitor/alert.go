@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transition is a change of the State of a website, passed to the Alerters.
+type Transition struct {
+	URL      string
+	From, To State
+	At       time.Time
+	// Status is the status of the website right after the transition.
+	Status Status
+}
+
+func (t Transition) String() string {
+	s := fmt.Sprintf("%s %s is %s (was %s), availability %.1f%%",
+		t.At.UTC().Format(time.RFC3339), t.URL, t.To, t.From, 100*t.Status.Availability)
+	if err := t.Status.Last.Err; err != nil {
+		s += fmt.Sprintf(", %s error: %v", t.Status.Last.Kind, err)
+	}
+	return s
+}
+
+// Alerter is notified of every Transition.
+type Alerter interface {
+	Alert(ctx context.Context, t Transition) error
+}
+
+// AlerterFunc lets an ordinary function be used as an Alerter, like http.HandlerFunc.
+type AlerterFunc func(ctx context.Context, t Transition) error
+
+func (f AlerterFunc) Alert(ctx context.Context, t Transition) error {
+	return f(ctx, t)
+}
+
+// WriterAlerter writes every Transition on its own line to W, e.g. os.Stdout.
+type WriterAlerter struct {
+	mu sync.Mutex // the transitions of different websites can happen at the same time
+	W  io.Writer
+}
+
+func (a *WriterAlerter) Alert(_ context.Context, t Transition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := fmt.Fprintln(a.W, t)
+	return err
+}
+
+// FileAlerter appends every Transition on its own line to the file at Path, creating it if needed.
+// The file is opened for every alert, so it can be rotated or deleted while the monitor runs.
+type FileAlerter struct {
+	mu   sync.Mutex
+	Path string
+}
+
+func (a *FileAlerter) Alert(_ context.Context, t Transition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Webhook POSTs every Transition as JSON to URL.
+type Webhook struct {
+	URL string
+	// Client sends the requests, http.DefaultClient if nil. Set its Timeout, as the alerts of a website wait for each other.
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body sent by Webhook.
+type webhookPayload struct {
+	URL          string    `json:"url"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	At           time.Time `json:"at"`
+	Availability float64   `json:"availability"`
+	Status       string    `json:"status,omitempty"`
+	ErrorKind    string    `json:"error_kind,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Message      string    `json:"message"`
+}
+
+func (a *Webhook) Alert(ctx context.Context, t Transition) error {
+	p := webhookPayload{
+		URL: t.URL, From: t.From.String(), To: t.To.String(), At: t.At.UTC(),
+		Availability: t.Status.Availability, Status: t.Status.Last.Status, ErrorKind: string(t.Status.Last.Kind),
+		Message: t.String(),
+	}
+	if err := t.Status.Last.Err; err != nil {
+		p.Error = err.Error()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body) // lets the connection be reused
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", a.URL, res.Status)
+	}
+	return nil
+}
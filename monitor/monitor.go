@@ -0,0 +1,250 @@
+// Package monitor keeps checking websites on their own intervals, tracks whether they are up or down and fires alerts when that changes.
+// It is the website checker of the concurrency lesson turned into a long running process: one goroutine per website, each sleeping on its own timer.
+package monitor
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+	"github.com/ISanviI/LearnGo/fetch"
+	"github.com/ISanviI/LearnGo/stats"
+)
+
+// State is whether a website is up or down.
+type State int
+
+const (
+	Unknown State = iota // not checked yet
+	Up
+	Down
+)
+
+func (s State) String() string {
+	switch s {
+	case Up:
+		return "UP"
+	case Down:
+		return "DOWN"
+	}
+	return "UNKNOWN"
+}
+
+// Target is a website to monitor, checked every Interval (30s if 0).
+type Target struct {
+	URL      string
+	Interval time.Duration
+}
+
+// SLO (service level objective) is what a website is expected to achieve over the rolling window of its checks.
+type SLO struct {
+	// Availability is the minimum fraction of successful checks, e.g. 0.99.
+	Availability float64
+	// Latency is the maximum LatencyPercentile-th percentile of the latencies of the successful checks, e.g. p95 <= 500ms.
+	// No latency objective if 0.
+	Latency           time.Duration
+	LatencyPercentile float64
+}
+
+// Status is a snapshot of a monitored website.
+type Status struct {
+	URL   string
+	State State
+	// Since is when State was entered.
+	Since time.Time
+	// Checks is the number of checks in the rolling window.
+	Checks       int
+	Availability float64
+	// Latency is the SLO.LatencyPercentile-th percentile of the successful checks in the window (p95 if there is no SLO).
+	Latency  time.Duration
+	MeetsSLO bool
+	// Last is the Result of the latest check.
+	Last fetch.Result
+}
+
+// Monitor checks websites until its context is done. Configure it before calling Run, and don't change it afterwards.
+type Monitor struct {
+	// Check fetches a website, the Fetch method of a fetch.Fetcher with a 10s timeout if nil.
+	Check func(ctx context.Context, url string) fetch.Result
+	// Clock is used for the intervals and the times of the transitions, clock.Real if nil.
+	Clock clock.Clock
+	// Window is the number of latest checks the availability and the latency are computed from, 100 if 0.
+	Window int
+	// Threshold is the number of consecutive checks needed to change the state, which stops a single slow check from flapping the state. 1 if 0.
+	Threshold int
+	SLO       SLO
+	// Alerters are called, one after another, on every transition except the first check finding a website up.
+	Alerters []Alerter
+	// OnAlertError is called with the errors of the Alerters, they are dropped if nil.
+	OnAlertError func(Transition, error)
+
+	mu      sync.Mutex
+	targets map[string]*target
+	order   []string // URLs in the order of Run, for Statuses
+}
+
+// check is what a Monitor remembers of a single check.
+type check struct {
+	ok      bool
+	latency time.Duration
+}
+
+type target struct {
+	Target
+	state  State
+	since  time.Time
+	streak int     // consecutive checks disagreeing with state
+	window []check // ring buffer of the latest checks
+	next   int     // index in window of the next check
+	last   fetch.Result
+}
+
+func (m *Monitor) clock() clock.Clock {
+	if m.Clock == nil {
+		return clock.Real{}
+	}
+	return m.Clock
+}
+
+// Run checks every target right away and then every target.Interval, until ctx is done, which is the error it returns.
+// Checks of different targets run concurrently, the checks of a single target never overlap. A URL listed twice is checked once, on its first interval.
+func (m *Monitor) Run(ctx context.Context, targets []Target) error {
+	checkFn := m.Check
+	if checkFn == nil {
+		f := &fetch.Fetcher{Timeout: 10 * time.Second}
+		checkFn = f.Fetch
+	}
+	window := m.Window
+	if window <= 0 {
+		window = 100
+	}
+
+	// A URL listed twice would get two goroutines sharing (and racing on) one target, so only its first Target is kept.
+	seen := make(map[string]bool, len(targets))
+	targets = slices.DeleteFunc(slices.Clone(targets), func(t Target) bool {
+		dup := seen[t.URL]
+		seen[t.URL] = true
+		return dup
+	})
+
+	m.mu.Lock()
+	if m.targets == nil {
+		m.targets = make(map[string]*target)
+	}
+	for _, t := range targets {
+		if _, ok := m.targets[t.URL]; !ok {
+			m.order = append(m.order, t.URL)
+		}
+		m.targets[t.URL] = &target{Target: t, window: make([]check, 0, window)}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		interval := t.Interval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		go func() {
+			defer wg.Done()
+			for {
+				res := checkFn(ctx, t.URL)
+				if ctx.Err() != nil {
+					// The check was cut short by the shutdown, it says nothing about the website.
+					return
+				}
+				if tr, ok := m.record(t.URL, res); ok {
+					m.alert(ctx, tr)
+				}
+				select {
+				case <-m.clock().After(interval):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// record adds res to the window of url, and returns the transition it caused if any.
+func (m *Monitor) record(url string, res fetch.Result) (Transition, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.targets[url]
+	c := check{ok: res.OK(), latency: res.Elapsed}
+	if len(t.window) < cap(t.window) {
+		t.window = append(t.window, c)
+	} else {
+		t.window[t.next] = c
+	}
+	t.next = (t.next + 1) % cap(t.window)
+	t.last = res
+
+	observed := Down
+	if c.ok {
+		observed = Up
+	}
+	if observed == t.state {
+		t.streak = 0
+		return Transition{}, false
+	}
+	t.streak++
+	if t.state != Unknown && t.streak < max(m.Threshold, 1) {
+		return Transition{}, false
+	}
+
+	from := t.state
+	t.state, t.since, t.streak = observed, m.clock().Now(), 0
+	if from == Unknown && observed == Up {
+		return Transition{}, false
+	}
+	return Transition{URL: url, From: from, To: observed, At: t.since, Status: m.status(t)}, true
+}
+
+func (m *Monitor) alert(ctx context.Context, tr Transition) {
+	for _, a := range m.Alerters {
+		if err := a.Alert(ctx, tr); err != nil && m.OnAlertError != nil {
+			m.OnAlertError(tr, err)
+		}
+	}
+}
+
+// Statuses returns the Status of every target, in the order they were passed to Run.
+func (m *Monitor) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]Status, 0, len(m.order))
+	for _, url := range m.order {
+		statuses = append(statuses, m.status(m.targets[url]))
+	}
+	return statuses
+}
+
+// status must be called with m.mu held.
+func (m *Monitor) status(t *target) Status {
+	s := Status{URL: t.URL, State: t.state, Since: t.since, Checks: len(t.window), Last: t.last}
+	var latencies []time.Duration
+	ok := 0
+	for _, c := range t.window {
+		if c.ok {
+			ok++
+			latencies = append(latencies, c.latency)
+		}
+	}
+	if len(t.window) > 0 {
+		s.Availability = float64(ok) / float64(len(t.window))
+	}
+	p := m.SLO.LatencyPercentile
+	if p <= 0 {
+		p = 95
+	}
+	slices.Sort(latencies)
+	s.Latency = stats.Percentile(latencies, p)
+	s.MeetsSLO = s.Checks > 0 && s.Availability >= m.SLO.Availability && (m.SLO.Latency == 0 || s.Latency <= m.SLO.Latency)
+	return s
+}
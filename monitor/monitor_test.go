@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// script is a fake Check answering every URL with its scripted outcomes in order (true is up), and up once they are used up.
+// Every check is sent on checked, so that a test knows when it happened.
+type script struct {
+	mu       sync.Mutex
+	outcomes map[string][]bool
+	checked  chan string
+}
+
+func newScript(outcomes map[string][]bool) *script {
+	return &script{outcomes: outcomes, checked: make(chan string, 100)}
+}
+
+func (s *script) check(_ context.Context, url string) fetch.Result {
+	s.mu.Lock()
+	ok := true
+	if o := s.outcomes[url]; len(o) > 0 {
+		ok, s.outcomes[url] = o[0], o[1:]
+	}
+	s.mu.Unlock()
+	defer func() { s.checked <- url }()
+	if ok {
+		return fetch.Result{Website: url, Status: "200 OK", StatusCode: 200, Elapsed: 100 * time.Millisecond}
+	}
+	return fetch.Result{Website: url, Err: errors.New("connection refused"), Kind: fetch.KindConnection, Elapsed: time.Millisecond}
+}
+
+// alerts records the transitions alerted on.
+type alerts struct {
+	mu          sync.Mutex
+	transitions []Transition
+}
+
+func (a *alerts) Alert(_ context.Context, t Transition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.transitions = append(a.transitions, t)
+	return nil
+}
+
+func (a *alerts) get() []Transition {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Transition(nil), a.transitions...)
+}
+
+// run starts m.Run in a goroutine, and returns a function stopping it and waiting for it to return.
+func run(t *testing.T, m *Monitor, targets ...Target) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx, targets) }()
+	return func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run returned %v, want context.Canceled", err)
+		}
+	}
+}
+
+// step waits for the check of every target, and for all of them to sleep again.
+func step(t *testing.T, fake *clock.Fake, s *script, targets int) {
+	t.Helper()
+	for range targets {
+		select {
+		case <-s.checked:
+		case <-time.After(2 * time.Second):
+			t.Fatal("no check happened")
+		}
+	}
+	fake.BlockUntil(targets)
+}
+
+func TestInterval(t *testing.T) {
+	fake := clock.NewFake(start)
+	s := newScript(nil)
+	m := &Monitor{Check: s.check, Clock: fake}
+	stop := run(t, m, Target{URL: "http://fast", Interval: 10 * time.Second}, Target{URL: "http://slow", Interval: time.Minute})
+	defer stop()
+
+	step(t, fake, s, 2) // both are checked right away
+	checks := map[string]int{}
+	for i := 1; i <= 6; i++ {
+		fake.Advance(10 * time.Second)
+		due := 1
+		if i == 6 {
+			due = 2 // a minute later, both are due
+		}
+		for range due {
+			select {
+			case url := <-s.checked:
+				checks[url]++
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%d checks after %ds, want %d", len(checks), i*10, due)
+			}
+		}
+		fake.BlockUntil(2)
+	}
+	if checks["http://fast"] != 6 || checks["http://slow"] != 1 {
+		t.Errorf("checks in a minute = %v, want 6 of the 10s target and 1 of the 1m target", checks)
+	}
+	if len(s.checked) != 0 {
+		t.Errorf("%d checks more than due", len(s.checked))
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	fake := clock.NewFake(start)
+	const url = "http://site"
+	// Up, a single failure (ignored with a threshold of 2), down, down (already alerted), up, up.
+	s := newScript(map[string][]bool{url: {true, false, true, false, false, false, true, true}})
+	a := &alerts{}
+	m := &Monitor{Check: s.check, Clock: fake, Threshold: 2, Alerters: []Alerter{a}}
+	stop := run(t, m, Target{URL: url, Interval: time.Second})
+	defer stop()
+
+	var states []State
+	step(t, fake, s, 1)
+	states = append(states, m.Statuses()[0].State)
+	for range 7 {
+		fake.Advance(time.Second)
+		step(t, fake, s, 1)
+		states = append(states, m.Statuses()[0].State)
+	}
+	want := []State{Up, Up, Up, Up, Down, Down, Down, Up}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+
+	// The first check finding the website up isn't alerted, every later transition is, once.
+	got := a.get()
+	if len(got) != 2 || got[0].From != Up || got[0].To != Down || got[1].From != Down || got[1].To != Up {
+		t.Fatalf("alerts = %v, want UP -> DOWN and DOWN -> UP", got)
+	}
+	if wantAt := start.Add(4 * time.Second); !got[0].At.Equal(wantAt) {
+		t.Errorf("went down at %s, want %s (the second failure in a row)", got[0].At, wantAt)
+	}
+	if got[0].Status.Last.Kind != fetch.KindConnection {
+		t.Errorf("the DOWN alert carries the result %v", got[0].Status.Last)
+	}
+}
+
+func TestSLO(t *testing.T) {
+	fake := clock.NewFake(start)
+	const url = "http://site"
+	s := newScript(map[string][]bool{url: {true, true, true, false}})
+	a := &alerts{}
+	m := &Monitor{Check: s.check, Clock: fake, Window: 4, Alerters: []Alerter{a},
+		SLO: SLO{Availability: 0.75, Latency: 200 * time.Millisecond, LatencyPercentile: 95}}
+	stop := run(t, m, Target{URL: url, Interval: time.Second})
+	defer stop()
+
+	step(t, fake, s, 1)
+	for range 3 {
+		fake.Advance(time.Second)
+		step(t, fake, s, 1)
+	}
+	// 3 of 4 checks succeeded in 100ms: the objectives are just met.
+	st := m.Statuses()[0]
+	if st.Checks != 4 || st.Availability != 0.75 || st.Latency != 100*time.Millisecond || !st.MeetsSLO {
+		t.Errorf("status = %+v, want 75%% availability meeting the SLO", st)
+	}
+	// The failure is alerted with the availability of the window.
+	if got := a.get(); len(got) != 1 || got[0].Status.Availability != 0.75 || !got[0].Status.MeetsSLO {
+		t.Errorf("alerts = %+v, want one DOWN alert at 75%% availability", got)
+	}
+
+	// The window only keeps the latest 4 checks, 2 failures of 4 miss the objective.
+	s.mu.Lock()
+	s.outcomes[url] = []bool{false}
+	s.mu.Unlock()
+	fake.Advance(time.Second)
+	step(t, fake, s, 1)
+	if st := m.Statuses()[0]; st.Checks != 4 || st.Availability != 0.5 || st.MeetsSLO {
+		t.Errorf("status = %+v, want 50%% availability missing the SLO", st)
+	}
+	if got := a.get(); len(got) != 1 {
+		t.Errorf("got %d alerts, want the website going down to be alerted once", len(got))
+	}
+}
+
+func TestDuplicateURLs(t *testing.T) {
+	fake := clock.NewFake(start)
+	s := newScript(nil)
+	m := &Monitor{Check: s.check, Clock: fake}
+	stop := run(t, m, Target{URL: "http://a", Interval: time.Second}, Target{URL: "http://a", Interval: time.Hour})
+	defer stop()
+
+	step(t, fake, s, 1)
+	fake.Advance(time.Second)
+	step(t, fake, s, 1)
+	if st := m.Statuses(); len(st) != 1 || st[0].Checks != 2 {
+		t.Errorf("statuses = %+v, want a single target checked on the interval of its first line", st)
+	}
+	if n := fake.Waiters(); n != 1 {
+		t.Errorf("%d goroutines sleeping, want 1", n)
+	}
+}
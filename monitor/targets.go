@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ISanviI/LearnGo/report"
+)
+
+// ReadTargets reads one target per line from r, in the format of report.ReadURLs with an optional interval after the URL:
+//
+//	https://google.com          # checked every defaultInterval
+//	https://example.com/health 10s
+func ReadTargets(r io.Reader, defaultInterval time.Duration) ([]Target, error) {
+	var targets []Target
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(report.StripComment(sc.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected a URL and an optional interval", n)
+		}
+		u, err := url.Parse(fields[0])
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("line %d: %q is not an absolute URL", n, fields[0])
+		}
+		t := Target{URL: fields[0], Interval: defaultInterval}
+		if len(fields) == 2 {
+			if t.Interval, err = time.ParseDuration(fields[1]); err != nil || t.Interval <= 0 {
+				return nil, fmt.Errorf("line %d: invalid interval %q", n, fields[1])
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, sc.Err()
+}
@@ -0,0 +1,29 @@
+// Package people holds the Person struct used by the custom data structures lesson.
+package people
+
+import (
+	"fmt"
+	"io"
+)
+
+// **Structs** - Group related data together.
+// Nested structs (another structure as a member in the current struct while specifying both a different variable name for it and the name of another struct) are also possible in Go.
+
+// Person is a named person with an age.
+type Person struct {
+	Name string
+	Age  int
+}
+
+// UpdateAge sets the age of p.
+// Methods on structs - the pointer receiver lets the method modify the original Person.
+func (p *Person) UpdateAge(age int) { // Method with receiver type Person
+	p.Age = age
+}
+
+// Greet writes the name and age of p to w.
+func Greet(w io.Writer, p Person) int {
+	fmt.Fprintln(w, "Hello, my name is "+p.Name)
+	fmt.Fprintln(w, "I am", p.Age, "years old")
+	return 1
+}
@@ -0,0 +1,268 @@
+// Package pipeline connects stages by channels: a Source sends values down the pipeline, every Stage turns them into other values and a Sink consumes them.
+//
+// Every stage runs in its own goroutines and closes its output channel when its input is exhausted, so the end of the input flows down the pipeline.
+// The first error of any stage cancels the context of the whole pipeline: every stage then stops, as it never sends nor receives without also watching ctx.Done().
+// That is what keeps a pipeline from leaking goroutines, however it stops.
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Source sends values down a pipeline using emit, which fails once the pipeline has been stopped.
+// It returns emit's error as is, or its own error to stop the pipeline.
+type Source[T any] func(ctx context.Context, emit func(T) error) error
+
+// Stage turns a value into another one, an error stops the pipeline.
+type Stage[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Sink consumes the values at the end of a pipeline, an error stops the pipeline.
+type Sink[T any] func(ctx context.Context, v T) error
+
+// Pipeline runs the goroutines of the stages and collects their first error, like an errgroup.
+// Build it using the functions of this package, then call Wait.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// New returns an empty pipeline, which stops when ctx is done.
+func New(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context of the pipeline, done once the pipeline stops.
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Go runs fn in a goroutine of the pipeline, its error stops the pipeline.
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// fail records the first error and cancels the context, which stops all the stages.
+func (p *Pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// Wait blocks until every goroutine of the pipeline has returned, and returns the first error, or the error of the parent context if it was done first.
+// A pipeline which ran to completion returns nil.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	// The goroutines have all returned, so a late error can't be set anymore.
+	p.fail(p.ctx.Err())
+	p.cancel()
+	return p.err
+}
+
+// Options configure a stage.
+type Options struct {
+	// Workers is the number of goroutines running the stage, 1 if less than 1: more than 1 fans the input out to them and merges their outputs back.
+	Workers int
+	// Buffer is the capacity of the output channel of the stage, 0 for unbuffered.
+	// A bounded buffer lets a stage run ahead of the next one by that many values, and no further.
+	Buffer int
+	// Ordered keeps the outputs in the order of the inputs when there are several workers.
+	// A slow value then holds back the ones after it, and at most Workers+Buffer values are in flight.
+	Ordered bool
+}
+
+// send sends v on ch unless ctx is done first.
+func send[T any](ctx context.Context, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Generate runs src as the first stage of p, and returns the channel it sends its values on.
+func Generate[T any](p *Pipeline, buffer int, src Source[T]) <-chan T {
+	out := make(chan T, max(buffer, 0))
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		return src(ctx, func(v T) error { return send(ctx, out, v) })
+	})
+	return out
+}
+
+// FromSlice is a Source sending the values of a slice.
+func FromSlice[T any](p *Pipeline, buffer int, values []T) <-chan T {
+	return Generate(p, buffer, func(ctx context.Context, emit func(T) error) error {
+		for _, v := range values {
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Map runs stage on every value of in using opts.Workers goroutines, and returns the channel of the outputs.
+func Map[In, Out any](p *Pipeline, in <-chan In, opts Options, stage Stage[In, Out]) <-chan Out {
+	workers := max(opts.Workers, 1)
+	out := make(chan Out, max(opts.Buffer, 0))
+	if opts.Ordered && workers > 1 {
+		mapOrdered(p, in, workers, out, stage)
+		return out
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		// The workers all receive from the same channel, which hands every value to one of them: that is the fan-out.
+		// They all send to the same channel too, that is the fan-in.
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range in {
+				res, err := stage(ctx, v)
+				if err != nil {
+					return err
+				}
+				if err := send(ctx, out, res); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	// Only the last worker to return may close out, as the others could still send on it.
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// mapOrdered gives every input a slot, a channel its output is sent on, and queues the slots in input order.
+// The outputs are then received slot by slot, so in input order, whichever worker finishes first.
+func mapOrdered[In, Out any](p *Pipeline, in <-chan In, workers int, out chan<- Out, stage Stage[In, Out]) {
+	type job struct {
+		in   In
+		slot chan Out
+	}
+	jobs := make(chan job)
+	// The capacity of slots bounds the values in flight, the dispatcher blocks once it is full.
+	slots := make(chan chan Out, workers+cap(out))
+
+	p.Go(func(ctx context.Context) error {
+		defer close(jobs)
+		defer close(slots)
+		for v := range in {
+			// Buffered, so that a worker never blocks on its slot even if the pipeline stops.
+			slot := make(chan Out, 1)
+			if err := send(ctx, slots, slot); err != nil {
+				return err
+			}
+			if err := send(ctx, jobs, job{in: v, slot: slot}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	for range workers {
+		p.Go(func(ctx context.Context) error {
+			for j := range jobs {
+				res, err := stage(ctx, j.in)
+				if err != nil {
+					return err
+				}
+				j.slot <- res
+			}
+			return nil
+		})
+	}
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for slot := range slots {
+			select {
+			case res := <-slot:
+				if err := send(ctx, out, res); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+// FanOut distributes the values of in to n channels, every value going to the first of them ready to receive it.
+func FanOut[T any](p *Pipeline, in <-chan T, n, buffer int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T, max(buffer, 0))
+		outs[i] = out
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			for v := range in {
+				if err := send(ctx, out, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return outs
+}
+
+// Merge sends the values of all the channels ins on a single channel, in the order they arrive, which is closed once they are all closed.
+func Merge[T any](p *Pipeline, buffer int, ins ...<-chan T) <-chan T {
+	out := make(chan T, max(buffer, 0))
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range in {
+				if err := send(ctx, out, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Drain runs sink on every value of in, as the last stage of p.
+func Drain[T any](p *Pipeline, in <-chan T, sink Sink[T]) {
+	p.Go(func(ctx context.Context) error {
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return nil
+				}
+				if err := sink(ctx, v); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
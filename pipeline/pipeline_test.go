@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+// collect runs the pipeline to its end, receiving the values of in.
+func collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var got []T
+	Drain(p, in, func(_ context.Context, v T) error {
+		got = append(got, v)
+		return nil
+	})
+	err := p.Wait()
+	return got, err
+}
+
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+func square(_ context.Context, v int) (int, error) { return v * v, nil }
+
+// jitter sleeps up to a millisecond, so that the workers finish out of order.
+func jitter(ctx context.Context, v int) (int, error) {
+	time.Sleep(time.Duration(rand.IntN(1000)) * time.Microsecond)
+	return v, nil
+}
+
+func TestMap(t *testing.T) {
+	defer leak.Verify(t)()
+	for _, opts := range []Options{{}, {Workers: 4}, {Workers: 4, Buffer: 2}} {
+		p := New(context.Background())
+		got, err := collect(p, Map(p, FromSlice(p, 0, seq(100)), opts, square))
+		if err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		slices.Sort(got)
+		for i, v := range got {
+			if v != i*i {
+				t.Fatalf("%+v: got %v, want the squares of 0..99", opts, got)
+			}
+		}
+	}
+}
+
+func TestMapOrdered(t *testing.T) {
+	defer leak.Verify(t)()
+	for _, opts := range []Options{{Workers: 1, Ordered: true}, {Workers: 8, Ordered: true}, {Workers: 8, Buffer: 3, Ordered: true}} {
+		p := New(context.Background())
+		got, err := collect(p, Map(p, FromSlice(p, 0, seq(200)), opts, jitter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, seq(200)) {
+			t.Errorf("%+v: the outputs aren't in the order of the inputs: %v", opts, got)
+		}
+	}
+}
+
+func TestFanOutMerge(t *testing.T) {
+	defer leak.Verify(t)()
+	p := New(context.Background())
+	outs := FanOut(p, FromSlice(p, 0, seq(100)), 3, 0)
+	got, err := collect(p, Merge(p, 0, outs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, seq(100)) {
+		t.Errorf("got %v, want every value once", got)
+	}
+}
+
+// endless is a Source which emits until the pipeline stops, and counts what it emitted.
+func endless(emitted *atomic.Int64) Source[int] {
+	return func(ctx context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			emitted.Add(1)
+		}
+	}
+}
+
+func TestStageError(t *testing.T) {
+	defer leak.Verify(t)()
+	errBad := errors.New("bad value")
+	for _, opts := range []Options{{}, {Workers: 4}, {Workers: 4, Ordered: true}, {Workers: 4, Buffer: 8}} {
+		p := New(context.Background())
+		var emitted atomic.Int64
+		out := Map(p, Generate(p, 0, endless(&emitted)), opts, func(ctx context.Context, v int) (int, error) {
+			if v == 50 {
+				return 0, errBad
+			}
+			return v, nil
+		})
+		got, err := collect(p, out)
+		if !errors.Is(err, errBad) {
+			t.Fatalf("%+v: Wait = %v, want the stage's error", opts, err)
+		}
+		// The error stopped the source, which would emit forever otherwise.
+		if n := emitted.Load(); n > 100 {
+			t.Errorf("%+v: the source emitted %d values after the error", opts, n)
+		}
+		if len(got) > 60 {
+			t.Errorf("%+v: the sink got %d values, the stages kept running after the error", opts, len(got))
+		}
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	defer leak.Verify(t)()
+	errFull := errors.New("full")
+	p := New(context.Background())
+	var emitted atomic.Int64
+	Drain(p, Map(p, Generate(p, 0, endless(&emitted)), Options{Workers: 2}, square), func(_ context.Context, v int) error {
+		if v > 100 {
+			return errFull
+		}
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, errFull) {
+		t.Errorf("Wait = %v, want the sink's error", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	defer leak.Verify(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	var emitted atomic.Int64
+	var received atomic.Int64
+	// A slow sink: the stages upstream are blocked sending when the pipeline is cancelled.
+	Drain(p, Map(p, Generate(p, 0, endless(&emitted)), Options{Workers: 4, Ordered: true}, square), func(ctx context.Context, v int) error {
+		received.Add(1)
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	done := make(chan error)
+	go func() { done <- p.Wait() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the pipeline didn't stop when its context was cancelled")
+	}
+	if received.Load() == 0 {
+		t.Error("nothing went through the pipeline before it was cancelled")
+	}
+	if err := p.Context().Err(); err == nil {
+		t.Error("the pipeline's context isn't done after Wait")
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	defer leak.Verify(t)()
+	errRead := errors.New("read failed")
+	p := New(context.Background())
+	src := Generate(p, 0, func(ctx context.Context, emit func(int) error) error {
+		for i := range 10 {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return errRead
+	})
+	got, err := collect(p, Map(p, src, Options{Workers: 3}, square))
+	if !errors.Is(err, errRead) {
+		t.Errorf("Wait = %v, want the source's error", err)
+	}
+	if len(got) > 10 {
+		t.Errorf("got %d values, want at most the 10 emitted", len(got))
+	}
+}
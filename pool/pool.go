@@ -0,0 +1,188 @@
+// Package pool runs a function on a stream of inputs using a fixed number of worker goroutines.
+// Unlike starting a goroutine per input, the number of goroutines (and of open connections, files, etc.) stays bounded however many inputs there are.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Submit once the pool has been closed.
+var ErrClosed = errors.New("pool: submit on closed pool")
+
+// Options configure a Pool.
+type Options struct {
+	// Workers is the number of goroutines calling the function, 1 if less than 1.
+	Workers int
+	// Queue is the number of submitted inputs waiting for a free worker, Submit blocks once it is full.
+	Queue int
+	// Ordered delivers the results in the order the inputs were submitted, instead of the order they finish in.
+	// A slow input then holds back the results after it, and Submit blocks once Workers+Queue results are waiting.
+	Ordered bool
+}
+
+type job[In any] struct {
+	seq int
+	in  In
+}
+
+type result[Out any] struct {
+	seq int
+	out Out
+	// skip marks a seq given up by Submit, so that ordered delivery doesn't wait for it forever.
+	skip bool
+}
+
+// Pool calls a function on every submitted input using Options.Workers goroutines.
+// The results must be received from Results while submitting, otherwise the workers block on sending them and Submit blocks in turn.
+type Pool[In, Out any] struct {
+	ctx     context.Context
+	fn      func(context.Context, In) Out
+	jobs    chan job[In]
+	done    chan result[Out] // results from the workers, in the order they finish
+	results chan Out
+	// window bounds the results waiting to be delivered in order, nil if unordered.
+	window chan struct{}
+
+	// mu guards closed, Submit holds a read lock while sending so that Close can't close jobs under it.
+	mu     sync.RWMutex
+	closed bool
+	// seq numbers the inputs, atomic as concurrent Submits only hold a read lock.
+	seq atomic.Int64
+	wg  sync.WaitGroup
+}
+
+// New starts the workers of a pool calling fn on every submitted input.
+// ctx is passed to every call of fn, cancelling it stops Submit from accepting inputs, while the queued ones are still handed to fn (which should return quickly once ctx is done).
+func New[In, Out any](ctx context.Context, opts Options, fn func(context.Context, In) Out) *Pool[In, Out] {
+	workers := max(opts.Workers, 1)
+	p := &Pool[In, Out]{
+		ctx:     ctx,
+		fn:      fn,
+		jobs:    make(chan job[In], max(opts.Queue, 0)),
+		done:    make(chan result[Out], workers),
+		results: make(chan Out),
+	}
+	if opts.Ordered {
+		p.window = make(chan struct{}, workers+max(opts.Queue, 0))
+	}
+
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	// The last worker to return closes done, which lets deliver close results.
+	go func() {
+		p.wg.Wait()
+		close(p.done)
+	}()
+	go p.deliver()
+	return p
+}
+
+func (p *Pool[In, Out]) work() {
+	defer p.wg.Done()
+	for j := range p.jobs {
+		p.done <- result[Out]{seq: j.seq, out: p.fn(p.ctx, j.in)}
+	}
+}
+
+// deliver forwards the results of the workers to Results, reordering them first if the pool is ordered.
+func (p *Pool[In, Out]) deliver() {
+	defer close(p.results)
+	if p.window == nil {
+		for r := range p.done {
+			if !r.skip {
+				p.results <- r.out
+			}
+		}
+		return
+	}
+
+	pending := make(map[int]result[Out])
+	next := 0
+	for r := range p.done {
+		pending[r.seq] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if !r.skip {
+				p.results <- r.out
+			}
+			<-p.window // frees the slot taken by Submit
+			next++
+		}
+	}
+}
+
+// Submit queues in for a worker, blocking while the queue is full.
+// It returns ErrClosed after Close, or the context's error once the pool's context is done.
+func (p *Pool[In, Out]) Submit(in In) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	// Checked first, as the selects below pick at random between a free worker and a done context.
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	if p.window != nil {
+		select {
+		case p.window <- struct{}{}:
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+
+	// seq is only needed for ordering, but it is cheap enough to always set.
+	j := job[In]{in: in, seq: int(p.seq.Add(1) - 1)}
+	select {
+	case p.jobs <- j:
+		return nil
+	case <-p.ctx.Done():
+		if p.window != nil {
+			// The seq is already taken, so ordered delivery would wait for it forever (and its window slot would never be freed).
+			p.done <- result[Out]{seq: j.seq, skip: true}
+		}
+		return p.ctx.Err()
+	}
+}
+
+// Close stops accepting inputs and lets the workers drain the queue.
+// Results is closed once the result of every submitted input has been delivered.
+// It is safe to call Close more than once.
+func (p *Pool[In, Out]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+}
+
+// Results returns the channel the results are delivered on.
+func (p *Pool[In, Out]) Results() <-chan Out {
+	return p.results
+}
+
+// Run submits every input to a new pool, closes it and returns its results.
+// Submitting happens in its own goroutine, so the results can be received straight away.
+// Inputs not yet submitted when ctx is done are dropped, so there can be fewer results than inputs.
+func Run[In, Out any](ctx context.Context, opts Options, inputs []In, fn func(context.Context, In) Out) <-chan Out {
+	p := New(ctx, opts, fn)
+	go func() {
+		defer p.Close()
+		for _, in := range inputs {
+			if p.Submit(in) != nil {
+				return
+			}
+		}
+	}()
+	return p.Results()
+}
@@ -0,0 +1,179 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+func inputs(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+// jitter returns its input after up to a millisecond, so that the workers finish out of order.
+func jitter(_ context.Context, v int) int {
+	time.Sleep(time.Duration(rand.IntN(1000)) * time.Microsecond)
+	return v
+}
+
+func TestRun(t *testing.T) {
+	defer leak.Verify(t)()
+	for _, opts := range []Options{{}, {Workers: 8}, {Workers: 8, Queue: 4}} {
+		var got []int
+		for v := range Run(context.Background(), opts, inputs(200), jitter) {
+			got = append(got, v)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, inputs(200)) {
+			t.Errorf("%+v: got %v, want a result per input", opts, got)
+		}
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	defer leak.Verify(t)()
+	for _, opts := range []Options{{Workers: 1, Ordered: true}, {Workers: 8, Ordered: true}, {Workers: 8, Queue: 4, Ordered: true}} {
+		var got []int
+		for v := range Run(context.Background(), opts, inputs(200), jitter) {
+			got = append(got, v)
+		}
+		if !slices.Equal(got, inputs(200)) {
+			t.Errorf("%+v: the results aren't in the order of the inputs: %v", opts, got)
+		}
+	}
+}
+
+func TestBoundedWorkers(t *testing.T) {
+	defer leak.Verify(t)()
+	var running, most atomic.Int64
+	fn := func(ctx context.Context, v int) int {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		return jitter(ctx, v)
+	}
+	for range Run(context.Background(), Options{Workers: 5, Queue: 100}, inputs(200), fn) {
+	}
+	if m := most.Load(); m != 5 {
+		t.Errorf("%d calls at once, want the 5 workers busy and no more", m)
+	}
+}
+
+func TestSubmitAfterClose(t *testing.T) {
+	defer leak.Verify(t)()
+	p := New(context.Background(), Options{Workers: 2}, jitter)
+	p.Close()
+	p.Close() // a second call is harmless
+	if err := p.Submit(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close = %v, want ErrClosed", err)
+	}
+	if _, ok := <-p.Results(); ok {
+		t.Error("Results isn't closed after Close")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	defer leak.Verify(t)()
+	for _, ordered := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{}, 1)
+		// The only worker is kept busy until ctx is done, so that the next Submit blocks.
+		p := New(ctx, Options{Workers: 1, Ordered: ordered}, func(ctx context.Context, v int) int {
+			started <- struct{}{}
+			<-ctx.Done()
+			return v
+		})
+		if err := p.Submit(1); err != nil {
+			t.Fatal(err)
+		}
+		<-started
+		submitted := make(chan error)
+		go func() { submitted <- p.Submit(2) }()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		if err := <-submitted; !errors.Is(err, context.Canceled) {
+			t.Errorf("ordered=%v: blocked Submit = %v, want context.Canceled", ordered, err)
+		}
+		if err := p.Submit(3); !errors.Is(err, context.Canceled) {
+			t.Errorf("ordered=%v: Submit after cancel = %v, want context.Canceled", ordered, err)
+		}
+		p.Close()
+		// Only the input handed to the worker has a result, the given up one doesn't hold back ordered delivery.
+		var got []int
+		for v := range p.Results() {
+			got = append(got, v)
+		}
+		if !slices.Equal(got, []int{1}) {
+			t.Errorf("ordered=%v: results %v, want [1]", ordered, got)
+		}
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	defer leak.Verify(t)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	for range Run(ctx, Options{Workers: 2}, inputs(10000), jitter) {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	if n >= 10000 {
+		t.Errorf("got all the %d results, want the inputs not submitted when ctx was cancelled dropped", n)
+	}
+}
+
+// BenchmarkRun compares a goroutine per input with pools of different sizes, on inputs waiting 100µs each like a fast I/O call:
+//
+//	go test -bench Run ./pool
+//
+// Every op handles benchInputs inputs. The fetch package has the same comparison on real HTTP requests (BenchmarkFetchAll).
+func BenchmarkRun(b *testing.B) {
+	const benchInputs = 1000
+	wait := func(_ context.Context, v int) int {
+		time.Sleep(100 * time.Microsecond)
+		return v
+	}
+	b.Run("unbounded", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			results := make(chan int)
+			for _, in := range inputs(benchInputs) {
+				go func() { results <- wait(context.Background(), in) }()
+			}
+			for range benchInputs {
+				<-results
+			}
+		}
+	})
+	for _, opts := range []Options{{Workers: 8}, {Workers: 64}, {Workers: 256}, {Workers: 64, Ordered: true}} {
+		name := fmt.Sprintf("pool-%d", opts.Workers)
+		if opts.Ordered {
+			name += "-ordered"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for range Run(context.Background(), opts, inputs(benchInputs), wait) {
+				}
+			}
+		})
+	}
+}
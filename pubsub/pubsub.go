@@ -0,0 +1,277 @@
+// Package pubsub is an in-process publish/subscribe broker: publishers send messages to topics, and every subscriber of a matching topic gets a copy.
+//
+// Topics are dot separated, like "check.example.com.down". A subscription pattern can use wildcards:
+// "*" matches exactly one part ("check.*.down") and ">" at the end matches one or more parts ("check.>").
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrClosed is returned when publishing or subscribing after Broker.Close.
+	ErrClosed = errors.New("pubsub: broker closed")
+	// ErrInvalidTopic is returned for empty topics or topic parts, and for wildcards in a published topic.
+	ErrInvalidTopic = errors.New("pubsub: invalid topic")
+)
+
+// Policy decides what happens when a message is published to a subscriber whose queue is full.
+type Policy int
+
+const (
+	// Block makes Publish wait until the subscriber has room, or until its context is done: a slow subscriber slows the publishers down (backpressure).
+	Block Policy = iota
+	// DropOldest makes room by dropping the oldest queued message, the subscriber always gets the latest messages.
+	DropOldest
+	// DropNewest drops the message being published, the subscriber keeps the messages already queued.
+	DropNewest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	}
+	return "unknown"
+}
+
+// Message is a published value and the topic it was published to.
+type Message[T any] struct {
+	Topic   string
+	Payload T
+}
+
+// Options configure a subscription.
+type Options struct {
+	// Buffer is the number of messages queued for the subscriber, 1 if less than 1.
+	Buffer int
+	// Policy applies when the queue is full.
+	Policy Policy
+}
+
+// Broker routes the published messages to the subscriptions, it is safe for concurrent use.
+// The zero value is not usable, use NewBroker.
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// NewBroker returns a broker without subscriptions.
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subs: map[*Subscription[T]]struct{}{}}
+}
+
+// Subscribe returns a subscription receiving the messages published to the topics matching pattern.
+func (b *Broker[T]) Subscribe(pattern string, opts Options) (*Subscription[T], error) {
+	parts, err := split(pattern, true)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscription[T]{
+		broker:  b,
+		pattern: parts,
+		policy:  opts.Policy,
+		ch:      make(chan Message[T], max(opts.Buffer, 1)),
+		done:    make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Handle subscribes to pattern and calls handler with every message in a goroutine of its own, until the returned function is called or the broker is closed.
+// The returned function waits for the handler to return, so handler mustn't call it itself.
+func (b *Broker[T]) Handle(pattern string, opts Options, handler func(Message[T])) (stop func(), err error) {
+	s, err := b.Subscribe(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for m := range s.C() {
+			handler(m)
+		}
+	}()
+	return func() {
+		s.Unsubscribe()
+		<-finished
+	}, nil
+}
+
+// Publish sends a message to every subscription matching topic, applying the policy of the subscriptions whose queue is full.
+// It only blocks for Block subscriptions, and returns ctx's error if ctx is done before they all had room; the message may have been delivered to some of them.
+func (b *Broker[T]) Publish(ctx context.Context, topic string, payload T) error {
+	parts, err := split(topic, false)
+	if err != nil {
+		return err
+	}
+	// The matching subscriptions are copied, so that the lock isn't held while blocking on a slow subscriber.
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	var matching []*Subscription[T]
+	for s := range b.subs {
+		if match(s.pattern, parts) {
+			matching = append(matching, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	m := Message[T]{Topic: topic, Payload: payload}
+	for _, s := range matching {
+		if err := s.deliver(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close unsubscribes every subscription and makes Publish and Subscribe fail with ErrClosed.
+// It is safe to call Close more than once.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs, b.closed = map[*Subscription[T]]struct{}{}, true
+	b.mu.Unlock()
+	for s := range subs {
+		s.close()
+	}
+}
+
+// Subscription is a queue of the messages published to the topics matching its pattern.
+type Subscription[T any] struct {
+	broker  *Broker[T]
+	pattern []string
+	policy  Policy
+	ch      chan Message[T]
+	dropped atomic.Uint64
+
+	// done is closed first when unsubscribing, which releases the publishers blocked on a full queue.
+	done chan struct{}
+	once sync.Once
+	// mu is held for reading while sending on ch, and for writing while closing it: ch is never closed under a send, which would panic.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// C returns the channel of the messages.
+// It is closed after Unsubscribe or Broker.Close, once the messages still queued have been received.
+func (s *Subscription[T]) C() <-chan Message[T] {
+	return s.ch
+}
+
+// Dropped returns the number of messages dropped because the queue was full.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the subscription and closes its channel, no message is queued after it returns.
+// It is safe to call Unsubscribe more than once, and concurrently with Publish.
+func (s *Subscription[T]) Unsubscribe() {
+	s.broker.mu.Lock()
+	delete(s.broker.subs, s)
+	s.broker.mu.Unlock()
+	s.close()
+}
+
+func (s *Subscription[T]) close() {
+	s.once.Do(func() {
+		close(s.done)
+		// Waits for the senders, which all return quickly now that done is closed.
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.ch)
+	})
+}
+
+// deliver queues m according to the policy, it only returns an error when blocking and ctx is done first.
+func (s *Subscription[T]) deliver(ctx context.Context, m Message[T]) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil // unsubscribed after Publish picked it
+	}
+
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.ch <- m:
+		default:
+			s.dropped.Add(1)
+		}
+		return nil
+
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- m:
+				return nil
+			default:
+			}
+			// Full: drop the oldest message, unless the subscriber just received it, and try again.
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+
+	select {
+	case s.ch <- m:
+		return nil
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// split splits a topic (or a pattern, with wildcards) into its parts.
+func split(topic string, wildcards bool) ([]string, error) {
+	if topic == "" {
+		return nil, ErrInvalidTopic
+	}
+	parts := strings.Split(topic, ".")
+	for i, p := range parts {
+		switch {
+		case p == "":
+			return nil, ErrInvalidTopic
+		case (p == "*" || p == ">") && !wildcards:
+			return nil, ErrInvalidTopic
+		case p == ">" && i != len(parts)-1:
+			return nil, ErrInvalidTopic // ">" only makes sense at the end
+		}
+	}
+	return parts, nil
+}
+
+// match reports whether the parts of a topic match the parts of a pattern.
+func match(pattern, topic []string) bool {
+	for i, p := range pattern {
+		if p == ">" {
+			return len(topic) > i
+		}
+		if i >= len(topic) || (p != "*" && p != topic[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
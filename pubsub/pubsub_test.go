@@ -0,0 +1,209 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/leak"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"check.a.down", "check.a.down", true},
+		{"check.a.down", "check.b.down", false},
+		{"check.a", "check.a.down", false},
+		{"check.a.down", "check.a", false},
+		{"check.*.down", "check.a.down", true},
+		{"check.*.down", "check.a.up", false},
+		{"check.*.down", "check.a.b.down", false}, // * is exactly one part
+		{"*", "check", true},
+		{"*", "check.a", false},
+		{"check.>", "check.a", true},
+		{"check.>", "check.a.b.down", true},
+		{"check.>", "check", false}, // > is at least one part
+		{">", "check.a", true},
+		{"*.>", "check", false},
+		{"*.>", "check.a", true},
+	}
+	for _, tt := range tests {
+		pattern, _ := split(tt.pattern, true)
+		topic, _ := split(tt.topic, false)
+		if got := match(pattern, topic); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidTopics(t *testing.T) {
+	b := NewBroker[int]()
+	defer b.Close()
+	for _, pattern := range []string{"", ".", "check.", ".check", "check..down", "check.>.down"} {
+		if _, err := b.Subscribe(pattern, Options{}); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Subscribe(%q) = %v, want ErrInvalidTopic", pattern, err)
+		}
+	}
+	// A wildcard is a whole part, "a>" is a plain part.
+	if _, err := b.Subscribe("check.a>", Options{}); err != nil {
+		t.Errorf("Subscribe(%q) = %v", "check.a>", err)
+	}
+	for _, topic := range []string{"", "check..down", "check.*.down", "check.>"} {
+		if err := b.Publish(context.Background(), topic, 1); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Publish(%q) = %v, want ErrInvalidTopic", topic, err)
+		}
+	}
+}
+
+// drain receives the messages queued on s after it is unsubscribed.
+func drain(s *Subscription[int]) []int {
+	s.Unsubscribe()
+	var got []int
+	for m := range s.C() {
+		got = append(got, m.Payload)
+	}
+	return got
+}
+
+func TestPolicies(t *testing.T) {
+	defer leak.Verify(t)()
+	tests := []struct {
+		policy      Policy
+		want        []int
+		wantDropped uint64
+	}{
+		{DropOldest, []int{3, 4, 5}, 2},
+		{DropNewest, []int{1, 2, 3}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			b := NewBroker[int]()
+			defer b.Close()
+			s, err := b.Subscribe("t", Options{Buffer: 3, Policy: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 5; i++ {
+				if err := b.Publish(context.Background(), "t", i); err != nil {
+					t.Fatalf("Publish %d: %v", i, err)
+				}
+			}
+			if s.Dropped() != tt.wantDropped {
+				t.Errorf("Dropped = %d, want %d", s.Dropped(), tt.wantDropped)
+			}
+			if got := drain(s); !slices.Equal(got, tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("block", func(t *testing.T) {
+		b := NewBroker[int]()
+		defer b.Close()
+		s, _ := b.Subscribe("t", Options{Buffer: 1, Policy: Block})
+		b.Publish(context.Background(), "t", 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := b.Publish(ctx, "t", 2); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Publish to a full queue = %v, want to block until the deadline", err)
+		}
+
+		// Receiving makes room for a blocked publisher.
+		published := make(chan error)
+		go func() { published <- b.Publish(context.Background(), "t", 3) }()
+		if m := <-s.C(); m.Payload != 1 {
+			t.Errorf("received %d, want 1", m.Payload)
+		}
+		if err := <-published; err != nil {
+			t.Errorf("Publish = %v", err)
+		}
+		if got := drain(s); !slices.Equal(got, []int{3}) || s.Dropped() != 0 {
+			t.Errorf("received %v with %d dropped, want [3] and nothing dropped", got, s.Dropped())
+		}
+	})
+}
+
+func TestRouting(t *testing.T) {
+	defer leak.Verify(t)()
+	b := NewBroker[int]()
+	defer b.Close()
+	all, _ := b.Subscribe(">", Options{Buffer: 10})
+	down, _ := b.Subscribe("check.*.down", Options{Buffer: 10})
+	a, _ := b.Subscribe("check.a.>", Options{Buffer: 10})
+	for i, topic := range []string{"check.a.down", "check.b.down", "check.a.up", "other"} {
+		b.Publish(context.Background(), topic, i)
+	}
+	for _, tt := range []struct {
+		name string
+		s    *Subscription[int]
+		want []int
+	}{{">", all, []int{0, 1, 2, 3}}, {"check.*.down", down, []int{0, 1}}, {"check.a.>", a, []int{0, 2}}} {
+		if got := drain(tt.s); !slices.Equal(got, tt.want) {
+			t.Errorf("%s received %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnsubscribeReleasesPublish(t *testing.T) {
+	defer leak.Verify(t)()
+	b := NewBroker[int]()
+	defer b.Close()
+	s, _ := b.Subscribe("t", Options{Buffer: 1})
+	b.Publish(context.Background(), "t", 1)
+
+	published := make(chan error)
+	go func() { published <- b.Publish(context.Background(), "t", 2) }()
+	// Publish is blocked on the full queue (or about to be), Unsubscribe must neither wait for it nor make it panic.
+	time.Sleep(20 * time.Millisecond)
+	s.Unsubscribe()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Errorf("Publish = %v, want nil once the subscriber is gone", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish stayed blocked after Unsubscribe")
+	}
+	if got := drain(s); !slices.Equal(got, []int{1}) {
+		t.Errorf("received %v, want the message queued before Unsubscribe", got)
+	}
+	s.Unsubscribe() // a second call is harmless
+}
+
+func TestClose(t *testing.T) {
+	defer leak.Verify(t)()
+	b := NewBroker[int]()
+	s, _ := b.Subscribe("t", Options{Buffer: 1})
+	var handled []int
+	stop, err := b.Handle("t", Options{Buffer: 10}, func(m Message[int]) { handled = append(handled, m.Payload) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Publish(context.Background(), "t", 1)
+
+	// A publisher blocked on s is released by Close.
+	published := make(chan error)
+	go func() { published <- b.Publish(context.Background(), "t", 2) }()
+	time.Sleep(20 * time.Millisecond)
+	b.Close()
+	<-published
+	stop() // the handler's goroutine has returned: the channel was closed
+	if len(handled) == 0 || handled[0] != 1 {
+		t.Errorf("handled %v, want 1 first", handled)
+	}
+	if got := drain(s); len(got) == 0 || got[0] != 1 {
+		t.Errorf("received %v after Close, want the queued messages", got)
+	}
+
+	if err := b.Publish(context.Background(), "t", 3); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close = %v, want ErrClosed", err)
+	}
+	if _, err := b.Subscribe("t", Options{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close = %v, want ErrClosed", err)
+	}
+	b.Close() // a second call is harmless
+}
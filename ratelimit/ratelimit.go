@@ -0,0 +1,199 @@
+// Package ratelimit limits the rate of requests using token buckets, per host and overall.
+//
+// A token bucket holds up to Burst tokens and is refilled at Rate tokens per second.
+// Every request takes a token: a full bucket lets a burst of requests through at once, after which they are spaced 1/Rate apart.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+)
+
+// ErrDeadline is returned by Wait when the token wouldn't be there before the context's deadline, so that waiting would be pointless.
+// It wraps context.DeadlineExceeded, as the result is the same as waiting until the deadline.
+var ErrDeadline = fmt.Errorf("ratelimit: waiting for a token would exceed the context's deadline: %w", context.DeadlineExceeded)
+
+// Limit is the rate and the burst size of a Bucket.
+type Limit struct {
+	// Rate is the number of tokens added per second, no limit if 0.
+	Rate float64
+	// Burst is the number of tokens the bucket holds, 1 if less than 1.
+	Burst int
+}
+
+// Bucket is a token bucket, safe for concurrent use.
+type Bucket struct {
+	limit Limit
+	clock clock.Clock
+
+	mu sync.Mutex
+	// tokens is negative when more tokens have been reserved than there are, the waiters are then queued up behind each other.
+	tokens float64
+	// last is when tokens was last brought up to date.
+	last time.Time
+}
+
+// NewBucket returns a full bucket, its refill uses c (clock.Real if nil).
+func NewBucket(limit Limit, c clock.Clock) *Bucket {
+	if c == nil {
+		c = clock.Real{}
+	}
+	limit.Burst = max(limit.Burst, 1)
+	return &Bucket{limit: limit, clock: c, tokens: float64(limit.Burst), last: c.Now()}
+}
+
+// refill adds the tokens accumulated since b.last, the caller holds b.mu.
+func (b *Bucket) refill() {
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// Allow takes a token if there is one, without waiting.
+func (b *Bucket) Allow() bool {
+	if b.limit.Rate <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reserve takes a token even if it isn't there yet, and returns how long to wait until it is.
+func (b *Bucket) reserve() time.Duration {
+	if b.limit.Rate <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// unreserve gives back a token taken by reserve, when its caller gave up waiting for it.
+func (b *Bucket) unreserve() {
+	if b.limit.Rate <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(float64(b.limit.Burst), b.tokens+1)
+}
+
+// Wait blocks until a token can be taken, or returns an error if ctx is done first.
+func (b *Bucket) Wait(ctx context.Context) error {
+	return wait(ctx, b.clock, b)
+}
+
+// wait takes a token from every bucket, and waits for the one which is available last.
+// Reserving upfront, rather than sleeping and retrying, keeps the waiters in order: none of them can starve.
+func wait(ctx context.Context, c clock.Clock, buckets ...*Bucket) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var d time.Duration
+	for _, b := range buckets {
+		d = max(d, b.reserve())
+	}
+	if d == 0 {
+		return nil
+	}
+	giveBack := func() {
+		for _, b := range buckets {
+			b.unreserve()
+		}
+	}
+	// The deadline is on the real clock, while d is the same duration on any clock.
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		giveBack()
+		return ErrDeadline
+	}
+	select {
+	case <-c.After(d):
+		return nil
+	case <-ctx.Done():
+		giveBack()
+		return ctx.Err()
+	}
+}
+
+// Limiter limits the requests to every host and the requests overall, its zero value doesn't limit anything.
+// The fields must be set before the first call to Wait or Allow.
+type Limiter struct {
+	// PerHost is the limit of every host, each host has its own bucket.
+	PerHost Limit
+	// Global is the limit of all the requests together.
+	Global Limit
+	// Clock refills the buckets and times the waits, clock.Real if nil.
+	Clock clock.Clock
+
+	mu     sync.Mutex
+	global *Bucket
+	// hosts holds a bucket for every host seen so far, it is never pruned as a process only talks to so many hosts.
+	hosts map[string]*Bucket
+}
+
+// buckets returns the buckets a request to host takes a token from.
+func (l *Limiter) buckets(host string) []*Bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Clock == nil {
+		l.Clock = clock.Real{}
+	}
+	var buckets []*Bucket
+	if l.PerHost.Rate > 0 {
+		b, ok := l.hosts[host]
+		if !ok {
+			if l.hosts == nil {
+				l.hosts = map[string]*Bucket{}
+			}
+			b = NewBucket(l.PerHost, l.Clock)
+			l.hosts[host] = b
+		}
+		buckets = append(buckets, b)
+	}
+	if l.Global.Rate > 0 {
+		if l.global == nil {
+			l.global = NewBucket(l.Global, l.Clock)
+		}
+		buckets = append(buckets, l.global)
+	}
+	return buckets
+}
+
+// Wait blocks until a request to host is allowed by both the host's and the global limit, or returns an error if ctx is done first.
+// When the wait would last past ctx's deadline, it returns ErrDeadline straight away.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	buckets := l.buckets(host)
+	return wait(ctx, l.Clock, buckets...)
+}
+
+// Allow reports whether a request to host is allowed right now, taking the tokens if it is.
+func (l *Limiter) Allow(host string) bool {
+	buckets := l.buckets(host)
+	for i, b := range buckets {
+		if !b.Allow() {
+			// The host's token is given back when the global bucket is empty, as the request isn't made.
+			for _, taken := range buckets[:i] {
+				taken.unreserve()
+			}
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ISanviI/LearnGo/clock"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// allowed returns how many of n calls to allow succeeded.
+func allowed(n int, allow func() bool) int {
+	ok := 0
+	for range n {
+		if allow() {
+			ok++
+		}
+	}
+	return ok
+}
+
+func TestBucketBurst(t *testing.T) {
+	b := NewBucket(Limit{Rate: 1, Burst: 3}, clock.NewFake(start))
+	if got := allowed(10, b.Allow); got != 3 {
+		t.Errorf("a full bucket allowed %d requests at once, want the burst of 3", got)
+	}
+	// A burst below 1 would never allow anything.
+	b = NewBucket(Limit{Rate: 1}, clock.NewFake(start))
+	if got := allowed(10, b.Allow); got != 1 {
+		t.Errorf("a bucket without a burst allowed %d requests at once, want 1", got)
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	fake := clock.NewFake(start)
+	b := NewBucket(Limit{Rate: 2, Burst: 4}, fake)
+	allowed(4, b.Allow)
+
+	fake.Advance(499 * time.Millisecond)
+	if b.Allow() {
+		t.Error("a token was added before 1/Rate")
+	}
+	fake.Advance(time.Millisecond)
+	if !b.Allow() {
+		t.Error("no token was added after 1/Rate")
+	}
+	// However long it was idle for, the bucket holds no more than Burst tokens.
+	fake.Advance(time.Hour)
+	if got := allowed(10, b.Allow); got != 4 {
+		t.Errorf("allowed %d requests after an hour, want the burst of 4", got)
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	var l Limiter
+	if got := allowed(1000, func() bool { return l.Allow("host") }); got != 1000 {
+		t.Errorf("the zero Limiter allowed %d of 1000 requests", got)
+	}
+	if err := l.Wait(context.Background(), "host"); err != nil {
+		t.Errorf("the zero Limiter: Wait = %v", err)
+	}
+}
+
+// waitAsync calls wait in a goroutine, and returns the channel its error is sent on.
+func waitAsync(ctx context.Context, wait func(context.Context) error) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- wait(ctx) }()
+	return done
+}
+
+func TestBucketWait(t *testing.T) {
+	fake := clock.NewFake(start)
+	b := NewBucket(Limit{Rate: 1, Burst: 1}, fake)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait on a full bucket: %v", err)
+	}
+
+	// The waiters queue up: the first gets the token of the next second, the second the one after.
+	first := waitAsync(context.Background(), b.Wait)
+	fake.BlockUntil(1)
+	second := waitAsync(context.Background(), b.Wait)
+	fake.BlockUntil(2)
+	fake.Advance(time.Second)
+	if err := <-first; err != nil {
+		t.Errorf("first waiter: %v", err)
+	}
+	select {
+	case <-second:
+		t.Fatal("the second waiter got a token after 1s")
+	case <-time.After(50 * time.Millisecond):
+	}
+	fake.Advance(time.Second)
+	if err := <-second; err != nil {
+		t.Errorf("second waiter: %v", err)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	fake := clock.NewFake(start)
+	b := NewBucket(Limit{Rate: 1, Burst: 1}, fake)
+	b.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, b.Wait)
+	fake.BlockUntil(1)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait didn't return when its context was cancelled")
+	}
+	// The token reserved by the cancelled wait was given back.
+	fake.Advance(time.Second)
+	if !b.Allow() {
+		t.Error("the token of the cancelled wait wasn't given back")
+	}
+
+	// An already cancelled context doesn't take a token.
+	if err := b.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait with a cancelled context = %v, want context.Canceled", err)
+	}
+	fake.Advance(time.Second)
+	if !b.Allow() {
+		t.Error("Wait with a cancelled context took a token")
+	}
+}
+
+func TestWaitDeadline(t *testing.T) {
+	fake := clock.NewFake(start)
+	b := NewBucket(Limit{Rate: 1, Burst: 1}, fake)
+	b.Allow()
+
+	// The next token is a second away, past the deadline: Wait returns without waiting for it.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := waitAsync(ctx, b.Wait)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrDeadline) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Wait = %v, want ErrDeadline wrapping context.DeadlineExceeded", err)
+		}
+	case <-time.After(50 * time.Millisecond):
+		t.Fatal("Wait waited although the token was past the deadline")
+	}
+	if n := fake.Waiters(); n != 0 {
+		t.Errorf("%d goroutines sleeping, want 0", n)
+	}
+	fake.Advance(time.Second)
+	if !b.Allow() {
+		t.Error("the token of the wait past the deadline wasn't given back")
+	}
+
+	// A deadline after the token is waited for.
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	b.Allow()
+	done = waitAsync(ctx, b.Wait)
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Wait with a later deadline = %v", err)
+	}
+}
+
+func TestLimiterPerHost(t *testing.T) {
+	fake := clock.NewFake(start)
+	l := &Limiter{PerHost: Limit{Rate: 1, Burst: 2}, Clock: fake}
+	for _, host := range []string{"a.example", "b.example"} {
+		if got := allowed(5, func() bool { return l.Allow(host) }); got != 2 {
+			t.Errorf("%s: allowed %d requests at once, want its own burst of 2", host, got)
+		}
+	}
+	fake.Advance(time.Second)
+	if !l.Allow("a.example") || !l.Allow("b.example") {
+		t.Error("the hosts' buckets weren't refilled independently")
+	}
+}
+
+func TestLimiterGlobal(t *testing.T) {
+	fake := clock.NewFake(start)
+	l := &Limiter{PerHost: Limit{Rate: 0.001, Burst: 1}, Global: Limit{Rate: 1, Burst: 1}, Clock: fake}
+	if !l.Allow("a.example") {
+		t.Fatal("the first request wasn't allowed")
+	}
+	// b.example's own bucket is full, the global one is empty.
+	if l.Allow("b.example") {
+		t.Fatal("the global limit was ignored")
+	}
+	// The token taken from b.example's bucket was given back, so the global refill is enough.
+	fake.Advance(time.Second)
+	if !l.Allow("b.example") {
+		t.Error("the host's token of a request refused by the global limit wasn't given back")
+	}
+
+	// Wait waits for the later of the two buckets: a.example's, about 1000s away.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := waitAsync(ctx, func(ctx context.Context) error { return l.Wait(ctx, "a.example") })
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	select {
+	case <-done:
+		t.Fatal("Wait returned when the global bucket had a token but the host's didn't")
+	case <-time.After(50 * time.Millisecond):
+	}
+	fake.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+}
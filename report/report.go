@@ -0,0 +1,155 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ISanviI/LearnGo/fetch"
+)
+
+// Record is the flat, machine readable form of a fetch.Result, one line of a JSON Lines or CSV report.
+// Durations are in milliseconds, as most dashboards expect plain numbers.
+// Redirects is the redirect chain as "301 http://example.com/ -> 302 https://example.com/", Warnings are joined by "; ",
+// and Change is only set by the checker when it remembers the websites between runs (see the changes package).
+type Record struct {
+	CheckedAt     time.Time `json:"checked_at"`
+	Website       string    `json:"website"`
+	OK            bool      `json:"ok"`
+	Status        string    `json:"status"`
+	StatusCode    int       `json:"status_code"`
+	ErrorKind     string    `json:"error_kind"`
+	Error         string    `json:"error"`
+	BodySize      int64     `json:"body_size"`
+	Truncated     bool      `json:"truncated"`
+	ContentHash   string    `json:"content_hash"`
+	Attempts      int       `json:"attempts"`
+	ElapsedMS     float64   `json:"elapsed_ms"`
+	DNSMS         float64   `json:"dns_ms"`
+	ConnectMS     float64   `json:"connect_ms"`
+	TLSMS         float64   `json:"tls_ms"`
+	TTFBMS        float64   `json:"ttfb_ms"`
+	TransferMS    float64   `json:"transfer_ms"`
+	Circuit       string    `json:"circuit"`
+	FinalURL      string    `json:"final_url"`
+	Redirects     string    `json:"redirects"`
+	TLSVersion    string    `json:"tls_version"`
+	CipherSuite   string    `json:"cipher_suite"`
+	CertExpiresAt string    `json:"cert_expires_at"`
+	Warnings      string    `json:"warnings"`
+	Change        string    `json:"change"`
+}
+
+// NewRecord flattens res, which was checked at the given time.
+func NewRecord(res fetch.Result, at time.Time) Record {
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	r := Record{
+		CheckedAt:   at.UTC(),
+		Website:     res.Website,
+		OK:          res.OK(),
+		Status:      res.Status,
+		StatusCode:  res.StatusCode,
+		ErrorKind:   string(res.Kind),
+		BodySize:    res.BodySize,
+		Truncated:   res.Truncated,
+		ContentHash: res.ContentHash,
+		Attempts:    len(res.Attempts),
+		ElapsedMS:   ms(res.Elapsed),
+		DNSMS:       ms(res.Phases.DNS),
+		ConnectMS:   ms(res.Phases.Connect),
+		TLSMS:       ms(res.Phases.TLS),
+		TTFBMS:      ms(res.Phases.TTFB),
+		TransferMS:  ms(res.Phases.Transfer),
+		Circuit:     string(res.Circuit),
+		FinalURL:    res.URL,
+		Warnings:    strings.Join(res.Warnings, "; "),
+	}
+	if res.Err != nil {
+		r.Error = res.Err.Error()
+	}
+	hops := make([]string, len(res.Redirects))
+	for i, h := range res.Redirects {
+		hops[i] = fmt.Sprintf("%d %s", h.StatusCode, h.URL)
+	}
+	r.Redirects = strings.Join(hops, " -> ")
+	if res.TLS != nil {
+		r.TLSVersion, r.CipherSuite = res.TLS.Version, res.TLS.CipherSuite
+		if !res.TLS.NotAfter.IsZero() {
+			r.CertExpiresAt = res.TLS.NotAfter.UTC().Format(time.RFC3339)
+		}
+	}
+	return r
+}
+
+// Writer writes Records in some format, Flush must be called once done.
+type Writer interface {
+	Write(Record) error
+	Flush() error
+}
+
+// JSONLines writes every Record as a JSON object on its own line.
+type JSONLines struct {
+	enc *json.Encoder
+}
+
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{enc: json.NewEncoder(w)}
+}
+
+func (j *JSONLines) Write(r Record) error {
+	return j.enc.Encode(r) // Encode adds the newline
+}
+
+// Flush does nothing, as every Write goes straight to the underlying writer.
+func (j *JSONLines) Flush() error {
+	return nil
+}
+
+// csvHeader holds the same names as the json tags of Record, in the same order.
+var csvHeader = []string{
+	"checked_at", "website", "ok", "status", "status_code", "error_kind", "error", "body_size", "truncated", "content_hash",
+	"attempts", "elapsed_ms", "dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "transfer_ms", "circuit", "final_url", "redirects", "tls_version",
+	"cipher_suite", "cert_expires_at", "warnings", "change",
+}
+
+// CSV writes Records as CSV rows, preceded by a header row.
+type CSV struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+func (c *CSV) Write(r Record) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	return c.w.Write([]string{
+		r.CheckedAt.Format(time.RFC3339Nano), r.Website, strconv.FormatBool(r.OK), r.Status, strconv.Itoa(r.StatusCode),
+		r.ErrorKind, r.Error, strconv.FormatInt(r.BodySize, 10), strconv.FormatBool(r.Truncated), r.ContentHash, strconv.Itoa(r.Attempts),
+		f(r.ElapsedMS), f(r.DNSMS), f(r.ConnectMS), f(r.TLSMS), f(r.TTFBMS), f(r.TransferMS), r.Circuit, r.FinalURL, r.Redirects, r.TLSVersion,
+		r.CipherSuite, r.CertExpiresAt, r.Warnings, r.Change,
+	})
+}
+
+// Flush writes the buffered rows, and the header if nothing was written, so that an empty report is still a valid CSV file.
+func (c *CSV) Flush() error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,49 @@
+// Package report reads the lists of URLs to check and writes the results of checking them as JSON Lines or CSV, for other tools (CI, dashboards) to consume.
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ReadURLs reads one URL per line from r.
+// Blank lines are skipped, and `#` starts a comment when it begins a line or follows a space (a `#` inside a URL is its fragment):
+//
+//	# search engines
+//	https://google.com
+//	https://duckduckgo.com  # no tracking
+//
+// Every URL must be absolute with a scheme and a host, otherwise an error naming its line is returned.
+func ReadURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := StripComment(sc.Text())
+		if line == "" {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("line %d: %q is not an absolute URL", n, line)
+		}
+		urls = append(urls, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// StripComment removes the comment (see ReadURLs) and the surrounding whitespace from line.
+func StripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			line = line[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,45 @@
+// Package shapes shows interfaces and polymorphism using a few 2D shapes.
+package shapes
+
+import (
+	"fmt"
+	"io"
+)
+
+// **Interfaces** - Define a contract that types can implement, more like a blueprint for methods.
+// For example like abstract classes in C++ (methods must be redefined in child classes during inheritance, provides a blueprint)
+
+// Shape is implemented by every type having an `Area() float64` method, there is no `implements` keyword in Go.
+type Shape interface {
+	Area() float64 // Method signature
+}
+
+// Circle is a Shape with the given radius.
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 {
+	return 3.14 * c.Radius * c.Radius // Implementing the Area method for Circle
+}
+
+// Rectangle is a Shape with the given width and height.
+type Rectangle struct {
+	Width, Height float64
+}
+
+func (r Rectangle) Area() float64 {
+	return r.Width * r.Height // Implementing the Area method for Rectangle
+}
+
+// PrintArea writes the area of s to w along with the name of its concrete type.
+func PrintArea(w io.Writer, s Shape) { // Function that takes an interface type
+	// Type Assertions
+	if circle, ok := s.(Circle); ok {
+		fmt.Fprintln(w, "Circle Area:", circle.Area()) // Calls the Area method of the Shape interface
+	} else if rectangle, ok := s.(Rectangle); ok {
+		fmt.Fprintln(w, "Rectangle Area:", rectangle.Area())
+	} else {
+		fmt.Fprintln(w, "Unknown shape type")
+	}
+}
@@ -0,0 +1,144 @@
+// Package shardmap implements ConcurrentMap, a map safe for concurrent use split into shards, each guarded by a sync.RWMutex.
+// A single lock around a map makes every goroutine wait for every other one, with shards two goroutines only wait for each other when their keys land in the same shard.
+// Like the readers of the mutexes lesson, any number of goroutines can read a shard at once, a writer has it to itself.
+package shardmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"runtime"
+	"sync"
+)
+
+// ConcurrentMap is a map from K to V safe for concurrent use, use New to create one.
+type ConcurrentMap[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   func(K) uint64
+}
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+	// The padding keeps the locks of neighbouring shards out of the same cache line, otherwise the CPUs locking them would still slow each other down.
+	_ [32]byte
+}
+
+// New returns an empty ConcurrentMap with the given number of shards, 4 × runtime.GOMAXPROCS(0) if shards < 1.
+// hash spreads the keys over the shards, e.g. String for string keys, nil hashes the fmt.Sprint of a key, which works for any key but is slow.
+func New[K comparable, V any](shards int, hash func(K) uint64) *ConcurrentMap[K, V] {
+	if shards < 1 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	if hash == nil {
+		hash = func(k K) uint64 { return String(fmt.Sprint(k)) }
+	}
+	m := &ConcurrentMap[K, V]{shards: make([]shard[K, V], shards), hash: hash}
+	for i := range m.shards {
+		m.shards[i].m = map[K]V{}
+	}
+	return m
+}
+
+// seed is random for every run of the program, so that the shards of given keys can't be predicted (and overloaded on purpose).
+var seed = maphash.MakeSeed()
+
+// String hashes a string key.
+func String(s string) uint64 { return maphash.String(seed, s) }
+
+// Int hashes an integer key.
+func Int[T ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr](n T) uint64 {
+	// A multiplicative hash: consecutive keys, the most common ones, land in different shards.
+	return uint64(n) * 0x9e3779b97f4a7c15
+}
+
+func (m *ConcurrentMap[K, V]) shard(k K) *shard[K, V] {
+	return &m.shards[m.hash(k)%uint64(len(m.shards))]
+}
+
+// Get returns the value of k, and whether it is in the map.
+func (m *ConcurrentMap[K, V]) Get(k K) (V, bool) {
+	s := m.shard(k)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[k]
+	return v, ok
+}
+
+// Set sets the value of k.
+func (m *ConcurrentMap[K, V]) Set(k K, v V) {
+	s := m.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[k] = v
+}
+
+// Delete removes k from the map.
+func (m *ConcurrentMap[K, V]) Delete(k K) {
+	s := m.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, k)
+}
+
+// LoadOrStore returns the value of k if it is in the map (and loaded true), otherwise it sets it to v and returns v.
+// A Get followed by a Set wouldn't do: another goroutine could set k in between, and its value would be overwritten.
+func (m *ConcurrentMap[K, V]) LoadOrStore(k K, v V) (actual V, loaded bool) {
+	s := m.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.m[k]; ok {
+		return old, true
+	}
+	s.m[k] = v
+	return v, false
+}
+
+// Compute replaces the value of k with the one returned by fn, called with the current value and whether k is in the map.
+// k is deleted when fn returns keep false. It returns the new value and whether k is in the map now.
+// fn runs with the shard locked, so the read-modify-write is atomic (e.g. incrementing a count), and fn must not use the map.
+func (m *ConcurrentMap[K, V]) Compute(k K, fn func(old V, ok bool) (v V, keep bool)) (V, bool) {
+	s := m.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.m[k]
+	v, keep := fn(old, ok)
+	if !keep {
+		delete(s.m, k)
+		var zero V
+		return zero, false
+	}
+	s.m[k] = v
+	return v, true
+}
+
+// Len returns the number of keys in the map.
+func (m *ConcurrentMap[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn with every key and value until fn returns false, in no particular order.
+// Every shard is copied under its read lock and fn is called on the copy, so fn can use the map, and a slow fn doesn't block the writers.
+// The copies of the shards are taken one after the other though, so Range doesn't see the whole map at a single point in time.
+func (m *ConcurrentMap[K, V]) Range(fn func(k K, v V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.RLock()
+		snapshot := make(map[K]V, len(s.m))
+		for k, v := range s.m {
+			snapshot[k] = v
+		}
+		s.mu.RUnlock()
+		for k, v := range snapshot {
+			if !fn(k, v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package shardmap
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	m := New[string, int](4, String)
+	if _, ok := m.Get("a"); ok {
+		t.Error("Get on an empty map found a value")
+	}
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 3)
+	if v, ok := m.Get("a"); !ok || v != 3 {
+		t.Errorf("Get(a) = %d, %v, want 3, true", v, ok)
+	}
+	if v, loaded := m.LoadOrStore("a", 10); !loaded || v != 3 {
+		t.Errorf("LoadOrStore of a present key = %d, %v, want 3, true", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("c", 10); loaded || v != 10 {
+		t.Errorf("LoadOrStore of a missing key = %d, %v, want 10, false", v, loaded)
+	}
+	m.Delete("b")
+	m.Delete("missing")
+	if m.Len() != 2 {
+		t.Errorf("Len = %d, want 2", m.Len())
+	}
+	if v, ok := m.Compute("a", func(old int, ok bool) (int, bool) { return old + 1, true }); !ok || v != 4 {
+		t.Errorf("Compute incrementing a = %d, %v, want 4, true", v, ok)
+	}
+	if _, ok := m.Compute("a", func(int, bool) (int, bool) { return 0, false }); ok {
+		t.Error("Compute returning keep false didn't delete the key")
+	}
+	got := map[string]int{}
+	m.Range(func(k string, v int) bool { got[k] = v; return true })
+	if len(got) != 1 || got["c"] != 10 {
+		t.Errorf("Range saw %v, want map[c:10]", got)
+	}
+}
+
+func TestDefaultHash(t *testing.T) {
+	type point struct{ x, y int }
+	m := New[point, string](0, nil)
+	m.Set(point{1, 2}, "a")
+	if v, ok := m.Get(point{1, 2}); !ok || v != "a" {
+		t.Errorf("Get = %q, %v with the default hash", v, ok)
+	}
+}
+
+// TestConcurrent hammers the map from many goroutines, run it with -race to also check that it doesn't race.
+// Every goroutine owns a range of keys, so the final contents are known, and they all count in the same keys with Compute.
+func TestConcurrent(t *testing.T) {
+	const goroutines, keys = 16, 500
+	m := New[int, int](8, Int[int])
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range keys {
+				k := g*keys + i
+				m.Set(k, k)
+				if v, ok := m.Get(k); !ok || v != k {
+					t.Errorf("Get(%d) = %d, %v right after Set", k, v, ok)
+				}
+				// The odd keys are deleted again.
+				if k%2 == 1 {
+					m.Delete(k)
+				}
+				m.Compute(-1-i%10, func(old int, _ bool) (int, bool) { return old + 1, true })
+				// A reader going through every shard while the others write.
+				if i%100 == 0 {
+					m.Range(func(int, int) bool { return true })
+					m.Len()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := m.Len(); n != goroutines*keys/2+10 {
+		t.Errorf("Len = %d, want %d", n, goroutines*keys/2+10)
+	}
+	for k := range goroutines * keys {
+		_, ok := m.Get(k)
+		if ok != (k%2 == 0) {
+			t.Fatalf("key %d present: %v, want only the even keys", k, ok)
+		}
+	}
+	for k := -10; k < 0; k++ {
+		if v, _ := m.Get(k); v != goroutines*keys/10 {
+			t.Errorf("count of %d = %d, want %d: increments were lost", k, v, goroutines*keys/10)
+		}
+	}
+}
+
+// The benchmarks compare ConcurrentMap with sync.Map and with a map behind a single RWMutex:
+//
+//	go test -bench Map -cpu 1,8 ./shardmap
+//
+// Every op is a Get or a Set of a random key among benchMapKeys, from GOMAXPROCS goroutines (b.RunParallel).
+
+const benchMapKeys = 10000
+
+// concurrentMap is what the benchmarks need from the three maps.
+type concurrentMap interface {
+	Get(k int) (int, bool)
+	Set(k, v int)
+}
+
+// lockedMap is the map of the mutexes lesson: a plain map behind a single lock.
+type lockedMap struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+func (m *lockedMap) Get(k int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[k]
+	return v, ok
+}
+
+func (m *lockedMap) Set(k, v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[k] = v
+}
+
+// syncMap adapts sync.Map, which is built for keys written once and read many times, or for goroutines using disjoint keys.
+type syncMap struct{ m sync.Map }
+
+func (m *syncMap) Get(k int) (int, bool) {
+	v, ok := m.m.Load(k)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *syncMap) Set(k, v int) { m.m.Store(k, v) }
+
+func BenchmarkMap(b *testing.B) {
+	maps := []struct {
+		name string
+		new  func() concurrentMap
+	}{
+		{"sharded", func() concurrentMap { return New[int, int](0, Int[int]) }},
+		{"sync.Map", func() concurrentMap { return &syncMap{} }},
+		{"single-lock", func() concurrentMap { return &lockedMap{m: map[int]int{}} }},
+	}
+	for _, mix := range []struct {
+		name  string
+		reads int
+	}{{"read-heavy", 90}, {"write-heavy", 10}} {
+		for _, m := range maps {
+			b.Run(fmt.Sprintf("%s/%s", mix.name, m.name), func(b *testing.B) { benchmarkMap(b, m.new(), mix.reads) })
+		}
+	}
+}
+
+func benchmarkMap(b *testing.B, m concurrentMap, readPercent int) {
+	for k := range benchMapKeys {
+		m.Set(k, k)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			k := rand.IntN(benchMapKeys)
+			if rand.IntN(100) < readPercent {
+				m.Get(k)
+			} else {
+				m.Set(k, k)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Package stats summarises latencies: min/max/mean, percentiles and an ASCII histogram.
+// Summing the latencies of concurrent requests doesn't say how long they took together (they overlap), so a Summary also carries the wall clock time of the whole run.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Summary describes a set of latencies measured during a run taking Wall in total.
+type Summary struct {
+	Count          int
+	Wall           time.Duration // from the start of the run to its end
+	Sum            time.Duration // of all the latencies, equal to Wall only if they ran one after another
+	Min, Max, Mean time.Duration
+	P50, P90, P99  time.Duration
+}
+
+// Summarize returns the Summary of latencies, measured during a run taking wall.
+// latencies isn't modified.
+func Summarize(latencies []time.Duration, wall time.Duration) Summary {
+	s := Summary{Count: len(latencies), Wall: wall}
+	if len(latencies) == 0 {
+		return s
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	for _, l := range sorted {
+		s.Sum += l
+	}
+	s.Min, s.Max = sorted[0], sorted[len(sorted)-1]
+	s.Mean = s.Sum / time.Duration(len(sorted))
+	s.P50 = Percentile(sorted, 50)
+	s.P90 = Percentile(sorted, 90)
+	s.P99 = Percentile(sorted, 99)
+	return s
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100, p 0 giving the minimum) of sorted using the nearest-rank method:
+// the smallest latency that is greater than or equal to p percent of the latencies.
+// sorted must be sorted in increasing order, 0 is returned if it is empty.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	// rank = ceil(p/100 * n), p is divided last as p*n is exact for the usual percentiles while p/100 isn't (0.9*10 = 9.000000000000002).
+	n := len(sorted)
+	rank := int(math.Ceil(p * float64(n) / 100))
+	rank = min(max(rank, 1), n)
+	return sorted[rank-1]
+}
+
+// Speedup returns how many times faster the run of fast was than the one of slow, comparing their wall clock times.
+func Speedup(slow, fast Summary) float64 {
+	if fast.Wall <= 0 {
+		return 0
+	}
+	return float64(slow.Wall) / float64(fast.Wall)
+}
+
+// Write writes s as a small table, with title as its heading.
+func (s Summary) Write(w io.Writer, title string) {
+	ms := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+	fmt.Fprintf(w, "%s (%d requests)\n", title, s.Count)
+	fmt.Fprintf(w, "  wall: %s, sum of latencies: %s\n", ms(s.Wall), ms(s.Sum))
+	fmt.Fprintf(w, "  min: %s, mean: %s, max: %s\n", ms(s.Min), ms(s.Mean), ms(s.Max))
+	fmt.Fprintf(w, "  p50: %s, p90: %s, p99: %s\n", ms(s.P50), ms(s.P90), ms(s.P99))
+}
+
+// Histogram writes an ASCII histogram of latencies with the given number of equally wide buckets between the minimum and the maximum.
+// The longest bar is width characters long.
+func Histogram(w io.Writer, latencies []time.Duration, buckets, width int) {
+	if len(latencies) == 0 || buckets < 1 {
+		return
+	}
+	lo, hi := slices.Min(latencies), slices.Max(latencies)
+	// +1 so that the maximum falls in the last bucket and not one past it, and so that the step is never 0.
+	step := (hi-lo)/time.Duration(buckets) + 1
+	counts := make([]int, buckets)
+	for _, l := range latencies {
+		counts[int((l-lo)/step)]++
+	}
+	most := slices.Max(counts)
+	for i, c := range counts {
+		from := lo + time.Duration(i)*step
+		bar := strings.Repeat("#", c*width/most)
+		fmt.Fprintf(w, "  %8s - %-8s | %-*s %d\n",
+			from.Round(time.Millisecond), (from + step).Round(time.Millisecond), width, bar, c)
+	}
+}
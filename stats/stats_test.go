@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ten := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"single sample p0", []time.Duration{7}, 0, 7},
+		{"single sample p50", []time.Duration{7}, 50, 7},
+		{"single sample p100", []time.Duration{7}, 100, 7},
+		{"p0 is the minimum", ten, 0, 1},
+		{"p50", ten, 50, 5},
+		{"p90 has no floating point surprise", ten, 90, 9},
+		{"p99.9", ten, 99.9, 10},
+		{"p100 is the maximum", ten, 100, 10},
+		// 33.4% of 3 samples is 1.002 samples, so it takes the 2nd one to cover it.
+		{"fractional rank rounds up", []time.Duration{1, 2, 3}, 33.4, 2},
+		{"p99.9 of 1000", seq(1000), 99.9, 999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("Percentile(%d samples, %v) = %d, want %d", len(tt.sorted), tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func seq(n int) []time.Duration {
+	s := make([]time.Duration, n)
+	for i := range s {
+		s[i] = time.Duration(i + 1)
+	}
+	return s
+}
+
+func TestSummarize(t *testing.T) {
+	latencies := []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond}
+	s := Summarize(latencies, 35*time.Millisecond)
+	want := Summary{Count: 3, Wall: 35 * time.Millisecond, Sum: 60 * time.Millisecond,
+		Min: 10 * time.Millisecond, Max: 30 * time.Millisecond, Mean: 20 * time.Millisecond,
+		P50: 20 * time.Millisecond, P90: 30 * time.Millisecond, P99: 30 * time.Millisecond}
+	if s != want {
+		t.Errorf("Summarize = %+v, want %+v", s, want)
+	}
+	if latencies[0] != 30*time.Millisecond {
+		t.Error("Summarize sorted its input")
+	}
+	if s := Summarize(nil, time.Second); s != (Summary{Wall: time.Second}) {
+		t.Errorf("Summarize(nil) = %+v", s)
+	}
+	if got := Speedup(Summary{Wall: 3 * time.Second}, Summary{Wall: time.Second}); got != 3 {
+		t.Errorf("Speedup = %v, want 3", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var sb strings.Builder
+	Histogram(&sb, []time.Duration{0, 0, 0, 10 * time.Millisecond}, 2, 6)
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "###### 3") || !strings.HasSuffix(lines[1], "##     1") {
+		t.Errorf("Histogram =\n%s", sb.String())
+	}
+}